@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidjosearaujo/gometric/metrics"
+	"github.com/gorilla/websocket"
+)
+
+// AuthConfig authenticates clients with API keys or HMAC signed JWTs, and
+// restricts fields to some of their roles.
+type AuthConfig struct {
+	// Whether clients without credentials, or a certificate mapped to a
+	// role, are served
+	AllowAnonymous bool `yaml:"allowAnonymous"`
+
+	APIKeys map[string]string `yaml:"apiKeys"` // Role of each key
+
+	JWT struct {
+		SecretFile string `yaml:"secretFile"` // HS256, HS384 or HS512 key
+		Issuer     string `yaml:"issuer"`     // Checked when set
+		Audience   string `yaml:"audience"`   // Checked when set
+		RoleClaim  string `yaml:"roleClaim"`
+	} `yaml:"jwt"`
+
+	// Roles allowed to resolve each field, named Type.field, or every field
+	// of an object type. Only applied when clients authenticate
+	Restrict map[string][]string `yaml:"restrict"`
+}
+
+// defaultRestrict is used when the configuration has no restrict section.
+// Processes are restricted by type, as sockets lead to them too.
+func defaultRestrict() map[string][]string {
+	return map[string][]string{
+		"Process":             {"admin"},
+		"Network.connections": {"admin"},
+		"Network.listeners":   {"admin"},
+		"Host.macs":           {"admin"},
+	}
+}
+
+// authEnabled tells whether clients are authenticated, with API keys, JWTs
+// or the roles of their certificates.
+func (c Config) authEnabled() bool {
+	return len(c.Auth.APIKeys) > 0 || c.Auth.JWT.SecretFile != "" || len(c.TLS.Roles) > 0
+}
+
+func (c AuthConfig) validate() []error {
+	var errs []error
+
+	for _, key := range sortedKeys(c.APIKeys) {
+		if key == "" {
+			errs = append(errs, errors.New("auth.apiKeys: empty key"))
+		}
+		if c.APIKeys[key] == "" {
+			errs = append(errs, errors.New("auth.apiKeys: a key has no role"))
+		}
+	}
+
+	if c.JWT.SecretFile != "" {
+		if _, err := readSecret(c.JWT.SecretFile); err != nil {
+			errs = append(errs, fmt.Errorf("auth.jwt.secretFile: %w", err))
+		}
+		if c.JWT.RoleClaim == "" {
+			errs = append(errs, errors.New("auth.jwt.roleClaim: empty claim"))
+		}
+	}
+
+	for _, field := range sortedKeys(c.Restrict) {
+		if !metrics.HasRuleTarget(field) {
+			errs = append(errs, fmt.Errorf("auth.restrict: unknown field or type %q", field))
+		}
+	}
+
+	return errs
+}
+
+// redacted returns c with its API keys masked, so that it can be printed.
+// Each key keeps its role, under a mask numbered in the order of the keys.
+func (c AuthConfig) redacted() AuthConfig {
+	if len(c.APIKeys) == 0 {
+		return c
+	}
+
+	keys := make(map[string]string, len(c.APIKeys))
+	for i, key := range sortedKeys(c.APIKeys) {
+		keys[fmt.Sprintf("<redacted %d>", i+1)] = c.APIKeys[key]
+	}
+	c.APIKeys = keys
+	return c
+}
+
+func readSecret(file string) ([]byte, error) {
+	secret, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	secret = bytes.TrimSpace(secret)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("%s is empty", file)
+	}
+	return secret, nil
+}
+
+// authenticator gives requests the role of their credentials and turns
+// away the ones it can not authenticate.
+type authenticator struct {
+	mu      sync.RWMutex
+	enabled bool
+	config  AuthConfig
+	secret  []byte
+}
+
+func newAuthenticator(config Config) (*authenticator, error) {
+	a := &authenticator{}
+	return a, a.update(config)
+}
+
+// update switches to the settings of config, and keeps the previous ones
+// when the JWT secret can not be read.
+func (a *authenticator) update(config Config) error {
+	var secret []byte
+	if config.Auth.JWT.SecretFile != "" {
+		var err error
+		if secret, err = readSecret(config.Auth.JWT.SecretFile); err != nil {
+			return err
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.enabled, a.config, a.secret = config.authEnabled(), config.Auth, secret
+	return nil
+}
+
+// authenticate requires requests to carry an API key or a JWT, either as a
+// bearer token or in the X-API-Key header, unless their client certificate
+// gave them a role or anonymous clients are allowed. WebSocket upgrades of
+// graphQLPath without credentials are let through, to authenticate with the
+// payload of connection_init in authenticateInit.
+func (a *authenticator) authenticate(next http.Handler, graphQLPath string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.mu.RLock()
+		enabled, config, secret := a.enabled, a.config, a.secret
+		a.mu.RUnlock()
+
+		if !enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, err := credentials(r.Header.Get("X-API-Key"), r.Header.Get("Authorization"))
+		if err != nil {
+			unauthorized(w, err.Error())
+			return
+		}
+
+		if token == "" {
+			if metrics.RoleFromContext(r.Context()) == "" && !config.AllowAnonymous && !isWebSocket(r, graphQLPath) {
+				unauthorized(w, "Missing credentials")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		role, err := config.role(token, secret)
+		if err != nil {
+			unauthorized(w, err.Error())
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(metrics.WithRole(r.Context(), role)))
+	})
+}
+
+// isWebSocket tells whether r is a WebSocket upgrade of graphQLPath.
+func isWebSocket(r *http.Request, graphQLPath string) bool {
+	return r.URL.Path == graphQLPath && websocket.IsWebSocketUpgrade(r)
+}
+
+// authenticateInit authenticates WebSocket connections with the API key or
+// JWT of their connectionParams, in X-API-Key or as a bearer token in
+// Authorization like in headers. Connections that already have a role from
+// their upgrade request keep it when the payload has no credentials.
+func (a *authenticator) authenticateInit(ctx context.Context, params map[string]interface{}) (context.Context, error) {
+	a.mu.RLock()
+	enabled, config, secret := a.enabled, a.config, a.secret
+	a.mu.RUnlock()
+
+	if !enabled {
+		return ctx, nil
+	}
+
+	apiKey, _ := param(params, "X-API-Key").(string)
+	authorization, _ := param(params, "Authorization").(string)
+	token, err := credentials(apiKey, authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	if token == "" {
+		if metrics.RoleFromContext(ctx) == "" && !config.AllowAnonymous {
+			return nil, errors.New("Missing credentials")
+		}
+		return ctx, nil
+	}
+
+	role, err := config.role(token, secret)
+	if err != nil {
+		return nil, err
+	}
+	return metrics.WithRole(ctx, role), nil
+}
+
+// credentials returns the token of an Authorization value, which must be a
+// bearer token, or else apiKey.
+func credentials(apiKey, authorization string) (string, error) {
+	if authorization == "" {
+		return apiKey, nil
+	}
+	scheme, token, _ := strings.Cut(authorization, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", errors.New("Authorization must be a bearer token")
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// param returns the value of the connection parameter called name, which
+// is looked up regardless of case like header names.
+func param(params map[string]interface{}, name string) interface{} {
+	for key, value := range params {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return nil
+}
+
+// role returns the role of an API key or of a JWT signed with secret.
+func (c AuthConfig) role(token string, secret []byte) (string, error) {
+	for key, role := range c.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return role, nil
+		}
+	}
+
+	if secret == nil || strings.Count(token, ".") != 2 {
+		return "", errors.New("Invalid API key")
+	}
+	claims, err := verifyJWT(token, secret, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("Invalid token: %w", err)
+	}
+
+	if c.JWT.Issuer != "" && claims["iss"] != c.JWT.Issuer {
+		return "", errors.New("Invalid token: wrong issuer")
+	}
+	if c.JWT.Audience != "" && !hasAudience(claims["aud"], c.JWT.Audience) {
+		return "", errors.New("Invalid token: wrong audience")
+	}
+	role, _ := claims[c.JWT.RoleClaim].(string)
+	if role == "" {
+		return "", fmt.Errorf("Invalid token: no %s claim", c.JWT.RoleClaim)
+	}
+	return role, nil
+}
+
+// jwtLeeway is the clock skew tolerated on the exp and nbf claims.
+const jwtLeeway = time.Minute
+
+// verifyJWT checks the HMAC signature and the validity period of a compact
+// JWT and returns its claims. Tokens must expire.
+func verifyJWT(token string, secret []byte, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	var newHash func() hash.Hash
+	switch header.Alg {
+	case "HS256":
+		newHash = sha256.New
+	case "HS384":
+		newHash = sha512.New384
+	case "HS512":
+		newHash = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+	mac := hmac.New(newHash, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errors.New("bad signature")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, errors.New("no exp claim")
+	}
+	if now.Add(-jwtLeeway).After(time.Unix(int64(exp), 0)) {
+		return nil, errors.New("expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(jwtLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("not valid yet")
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.New("malformed")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return errors.New("malformed")
+	}
+	return nil
+}
+
+// hasAudience tells whether the aud claim, a string or a list of them,
+// holds audience.
+func hasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="gometric"`)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]interface{}{{
+			"message":    message,
+			"extensions": map[string]string{"code": "UNAUTHENTICATED"},
+		}},
+	})
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"hash"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidjosearaujo/gometric/metrics"
+)
+
+var testSecret = []byte("supersecret")
+
+// signJWT returns a compact JWT of claims signed with secret, the header
+// saying alg and the signature made with hashFn.
+func signJWT(t *testing.T, alg string, hashFn func() hash.Hash, secret []byte, claims map[string]interface{}) string {
+	t.Helper()
+
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signed := encode(map[string]string{"alg": alg, "typ": "JWT"}) + "." + encode(claims)
+	mac := hmac.New(hashFn, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyJWT(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	valid := map[string]interface{}{"role": "admin", "exp": now.Add(time.Hour).Unix()}
+
+	tests := []struct {
+		name   string
+		token  string
+		errMsg string // Empty when the token is valid
+	}{
+		{"HS256", signJWT(t, "HS256", sha256.New, testSecret, valid), ""},
+		{"HS384", signJWT(t, "HS384", sha512.New384, testSecret, valid), ""},
+		{"HS512", signJWT(t, "HS512", sha512.New, testSecret, valid), ""},
+		{"alg none", signJWT(t, "none", sha256.New, testSecret, valid), `unsupported algorithm "none"`},
+		{"alg RS256", signJWT(t, "RS256", sha256.New, testSecret, valid), `unsupported algorithm "RS256"`},
+		{"header and hash disagree", signJWT(t, "HS512", sha256.New, testSecret, valid), "bad signature"},
+		{"other secret", signJWT(t, "HS256", sha256.New, []byte("othersecret"), valid), "bad signature"},
+		{"no exp", signJWT(t, "HS256", sha256.New, testSecret, map[string]interface{}{"role": "admin"}), "no exp claim"},
+		{"expired", signJWT(t, "HS256", sha256.New, testSecret, map[string]interface{}{"exp": now.Add(-2 * jwtLeeway).Unix()}), "expired"},
+		{"expired within leeway", signJWT(t, "HS256", sha256.New, testSecret, map[string]interface{}{"exp": now.Add(-jwtLeeway / 2).Unix()}), ""},
+		{"not valid yet", signJWT(t, "HS256", sha256.New, testSecret, map[string]interface{}{"exp": now.Add(time.Hour).Unix(), "nbf": now.Add(2 * jwtLeeway).Unix()}), "not valid yet"},
+		{"two segments", "e30.e30", "malformed"},
+		{"bad base64", "!!.e30.e30", "malformed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifyJWT(tt.token, testSecret, now)
+			switch {
+			case tt.errMsg == "" && err != nil:
+				t.Fatalf("verifyJWT() = %v, want no error", err)
+			case tt.errMsg != "" && (err == nil || err.Error() != tt.errMsg):
+				t.Fatalf("verifyJWT() = %v, want %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestVerifyJWTTampered(t *testing.T) {
+	token := signJWT(t, "HS256", sha256.New, testSecret, map[string]interface{}{"role": "viewer", "exp": time.Now().Add(time.Hour).Unix()})
+	parts := strings.Split(token, ".")
+	claims, _ := json.Marshal(map[string]interface{}{"role": "admin", "exp": time.Now().Add(time.Hour).Unix()})
+	parts[1] = base64.RawURLEncoding.EncodeToString(claims)
+
+	if _, err := verifyJWT(strings.Join(parts, "."), testSecret, time.Now()); err == nil || err.Error() != "bad signature" {
+		t.Fatalf("verifyJWT() = %v, want bad signature", err)
+	}
+}
+
+func TestAuthConfigRole(t *testing.T) {
+	var config AuthConfig
+	config.APIKeys = map[string]string{"readerkey": "reader"}
+	config.JWT.Issuer = "issuer"
+	config.JWT.Audience = "gometric"
+	config.JWT.RoleClaim = "role"
+
+	exp := time.Now().Add(time.Hour).Unix()
+	jwt := func(claims map[string]interface{}) string {
+		return signJWT(t, "HS256", sha256.New, testSecret, claims)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		secret []byte
+		role   string // Empty when the token is refused
+	}{
+		{"API key", "readerkey", nil, "reader"},
+		{"unknown API key", "otherkey", testSecret, ""},
+		{"JWT", jwt(map[string]interface{}{"role": "admin", "iss": "issuer", "aud": "gometric", "exp": exp}), testSecret, "admin"},
+		{"JWT with audiences", jwt(map[string]interface{}{"role": "admin", "iss": "issuer", "aud": []string{"other", "gometric"}, "exp": exp}), testSecret, "admin"},
+		{"JWT without JWT key", jwt(map[string]interface{}{"role": "admin", "iss": "issuer", "aud": "gometric", "exp": exp}), nil, ""},
+		{"wrong issuer", jwt(map[string]interface{}{"role": "admin", "iss": "other", "aud": "gometric", "exp": exp}), testSecret, ""},
+		{"wrong audience", jwt(map[string]interface{}{"role": "admin", "iss": "issuer", "aud": "other", "exp": exp}), testSecret, ""},
+		{"no role", jwt(map[string]interface{}{"iss": "issuer", "aud": "gometric", "exp": exp}), testSecret, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, err := config.role(tt.token, tt.secret)
+			if tt.role == "" {
+				if err == nil {
+					t.Fatalf("role() = %q, want an error", role)
+				}
+				return
+			}
+			if err != nil || role != tt.role {
+				t.Fatalf("role() = %q, %v, want %q", role, err, tt.role)
+			}
+		})
+	}
+}
+
+func TestAuthConfigRedacted(t *testing.T) {
+	var config AuthConfig
+	config.APIKeys = map[string]string{"secretadminkey": "admin", "secretreaderkey": "reader"}
+
+	redacted := config.redacted()
+	want := map[string]string{"<redacted 1>": "admin", "<redacted 2>": "reader"}
+	if !reflect.DeepEqual(redacted.APIKeys, want) {
+		t.Errorf("redacted() API keys = %v, want %v", redacted.APIKeys, want)
+	}
+	if config.APIKeys["secretadminkey"] != "admin" {
+		t.Errorf("redacted() changed the API keys to %v", config.APIKeys)
+	}
+}
+
+func TestAuthenticateInit(t *testing.T) {
+	var config Config
+	config.Auth.APIKeys = map[string]string{"adminkey": "admin"}
+	a, err := newAuthenticator(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Auth.AllowAnonymous = true
+	anonymous, _ := newAuthenticator(config)
+	disabled, _ := newAuthenticator(Config{})
+
+	fromCert := metrics.WithRole(context.Background(), "reader")
+
+	tests := []struct {
+		name    string
+		auth    *authenticator
+		ctx     context.Context
+		params  map[string]interface{}
+		role    string
+		wantErr bool
+	}{
+		{"API key", a, context.Background(), map[string]interface{}{"X-API-Key": "adminkey"}, "admin", false},
+		{"bearer token", a, context.Background(), map[string]interface{}{"authorization": "Bearer adminkey"}, "admin", false},
+		{"not a bearer token", a, context.Background(), map[string]interface{}{"Authorization": "Basic adminkey"}, "", true},
+		{"unknown API key", a, context.Background(), map[string]interface{}{"X-API-Key": "otherkey"}, "", true},
+		{"no credentials", a, context.Background(), nil, "", true},
+		{"role of the upgrade request", a, fromCert, map[string]interface{}{}, "reader", false},
+		{"credentials over the upgrade request", a, fromCert, map[string]interface{}{"X-API-Key": "adminkey"}, "admin", false},
+		{"anonymous", anonymous, context.Background(), nil, "", false},
+		{"authentication off", disabled, context.Background(), nil, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := tt.auth.authenticateInit(tt.ctx, tt.params)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("authenticateInit() gave role %q, want an error", metrics.RoleFromContext(ctx))
+				}
+				return
+			}
+			if err != nil || metrics.RoleFromContext(ctx) != tt.role {
+				t.Fatalf("authenticateInit() = %q, %v, want %q", metrics.RoleFromContext(ctx), err, tt.role)
+			}
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidjosearaujo/gometric/metrics"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the server, read in order of precedence
+// from flags, GOMETRIC_* variables and a YAML file.
+type Config struct {
+	Listen      string `yaml:"listen"`
+	Path        string `yaml:"path"`
+	MetricsPath string `yaml:"metricsPath"`
+
+	// How long requests in flight are waited for when stopping
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+
+	Sampling struct {
+		Interval  time.Duration `yaml:"interval"`
+		Retention time.Duration `yaml:"retention"`
+	} `yaml:"sampling"`
+
+	Collectors struct {
+		Disabled []string          `yaml:"disabled"`
+		TTL      map[string]string `yaml:"ttl"` // Durations, or -1 to cache forever
+	} `yaml:"collectors"`
+
+	// Bounds of the operations clients may run, 0 lifting a limit
+	Limits struct {
+		MaxDepth   int `yaml:"maxDepth"`
+		MaxAliases int `yaml:"maxAliases"`
+		MaxCost    int `yaml:"maxCost"`
+	} `yaml:"limits"`
+
+	TLS  TLSConfig  `yaml:"tls"`
+	Auth AuthConfig `yaml:"auth"`
+
+	Roots struct {
+		Proc   string `yaml:"proc"`
+		Sys    string `yaml:"sys"`
+		Etc    string `yaml:"etc"`
+		Run    string `yaml:"run"`
+		Cgroup string `yaml:"cgroup"`
+	} `yaml:"roots"`
+}
+
+func defaultConfig() Config {
+	var c Config
+	c.Listen = ":7000"
+	c.Path = "/gometric"
+	c.MetricsPath = "/metrics"
+	c.ShutdownTimeout = 30 * time.Second
+	c.Limits.MaxDepth = metrics.DefaultLimits.MaxDepth
+	c.Limits.MaxAliases = metrics.DefaultLimits.MaxAliases
+	c.Limits.MaxCost = metrics.DefaultLimits.MaxCost
+	c.Auth.JWT.RoleClaim = "role"
+	c.Sampling.Interval = metrics.DefaultSampleInterval
+	c.Sampling.Retention = metrics.DefaultRetention
+
+	c.Collectors.TTL = make(map[string]string)
+	for name, ttl := range metrics.DefaultTTLs {
+		c.Collectors.TTL[name] = formatTTL(ttl)
+	}
+
+	fs := metrics.DefaultFS()
+	c.Roots.Proc = fs.Proc
+	c.Roots.Sys = fs.Sys
+	c.Roots.Etc = fs.Etc
+	c.Roots.Run = fs.Run
+	return c
+}
+
+// loadConfig merges the defaults, the file given with -config or
+// GOMETRIC_CONFIG, the GOMETRIC_* variables named after the flags and the
+// flags in args. printConfig tells whether -print-config was given.
+func loadConfig(args []string) (c Config, printConfig bool, err error) {
+	var file string
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	flags.StringVar(&file, "config", os.Getenv("GOMETRIC_CONFIG"), "YAML configuration file, also set with GOMETRIC_CONFIG")
+	flags.BoolVar(&printConfig, "print-config", false, "Print the effective configuration as YAML and exit")
+
+	c = defaultConfig()
+	flags.StringVar(&c.Listen, "listen", c.Listen, "Address the server listens on")
+	flags.StringVar(&c.Path, "path", c.Path, "Path of the GraphQL endpoint")
+	flags.StringVar(&c.MetricsPath, "metrics-path", c.MetricsPath, "Path of the Prometheus endpoint")
+	flags.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout, "How long requests in flight are waited for when stopping")
+	flags.DurationVar(&c.Sampling.Interval, "interval", c.Sampling.Interval, "Time between two background samples")
+	flags.DurationVar(&c.Sampling.Retention, "retention", c.Sampling.Retention, "How long samples are kept in memory")
+	flags.Var((*listValue)(&c.Collectors.Disabled), "disable-collectors", "Comma separated collectors to turn off, among "+strings.Join(metrics.Collectors.Names(), ", "))
+	flags.Var((*ttlValue)(&c.Collectors.TTL), "cache-ttl", "Comma separated name=duration pairs overriding how long the snapshot of a collector is reused (e.g. cpu=5s,host=0), -1 caches it forever")
+	flags.IntVar(&c.Limits.MaxDepth, "max-depth", c.Limits.MaxDepth, "Deepest nesting of fields an operation may have, 0 for no limit")
+	flags.IntVar(&c.Limits.MaxAliases, "max-aliases", c.Limits.MaxAliases, "Most aliases an operation may have, 0 for no limit")
+	flags.IntVar(&c.Limits.MaxCost, "max-cost", c.Limits.MaxCost, "Highest cost an operation may have, 0 for no limit")
+	flags.StringVar(&c.TLS.Cert, "tls-cert", c.TLS.Cert, "Certificate file, the endpoints are served over TLS when set with -tls-key")
+	flags.StringVar(&c.TLS.Key, "tls-key", c.TLS.Key, "Private key file of the certificate")
+	flags.StringVar(&c.TLS.ClientCA, "tls-client-ca", c.TLS.ClientCA, "CA bundle client certificates are verified against")
+	flags.BoolVar(&c.TLS.RequireClientCert, "tls-require-client-cert", c.TLS.RequireClientCert, "Reject clients without a certificate signed by -tls-client-ca")
+	flags.BoolVar(&c.Auth.AllowAnonymous, "allow-anonymous", c.Auth.AllowAnonymous, "Serve clients without credentials when authentication is set up")
+	flags.StringVar(&c.Auth.JWT.SecretFile, "jwt-secret-file", c.Auth.JWT.SecretFile, "File holding the key JWTs are signed with")
+	flags.StringVar(&c.Auth.JWT.Issuer, "jwt-issuer", c.Auth.JWT.Issuer, "Issuer JWTs must have")
+	flags.StringVar(&c.Auth.JWT.Audience, "jwt-audience", c.Auth.JWT.Audience, "Audience JWTs must have")
+	flags.StringVar(&c.Roots.Proc, "proc-root", c.Roots.Proc, "Where procfs is mounted, also set with HOST_PROC")
+	flags.StringVar(&c.Roots.Sys, "sys-root", c.Roots.Sys, "Where sysfs is mounted, also set with HOST_SYS")
+	flags.StringVar(&c.Roots.Etc, "etc-root", c.Roots.Etc, "Where the /etc of the host is, also set with HOST_ETC")
+	flags.StringVar(&c.Roots.Run, "run-root", c.Roots.Run, "Where the /run of the host is, also set with HOST_RUN")
+	flags.StringVar(&c.Roots.Cgroup, "cgroup-root", c.Roots.Cgroup, "Where the cgroup v2 hierarchy is mounted, looked for in /sys/fs/cgroup by default")
+
+	// The flags are parsed a first time for the file, which is then
+	// overridden by the variables and the flags parsed again
+	if err := flags.Parse(args); err != nil {
+		return c, false, err
+	}
+
+	c = defaultConfig()
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return c, false, err
+		}
+		defer f.Close()
+
+		decoder := yaml.NewDecoder(f)
+		decoder.KnownFields(true)
+		// An empty file is fine
+		if err := decoder.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+			return c, false, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	// Set here rather than in the defaults so that the restrictions of the
+	// file replace them instead of adding to them
+	if c.Auth.Restrict == nil {
+		c.Auth.Restrict = defaultRestrict()
+	}
+
+	var envErr error
+	flags.VisitAll(func(f *flag.Flag) {
+		if f.Name == "config" || f.Name == "print-config" {
+			return
+		}
+		name := "GOMETRIC_" + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if value, ok := os.LookupEnv(name); ok {
+			if err := f.Value.Set(value); err != nil && envErr == nil {
+				envErr = fmt.Errorf("invalid value %q for %s: %w", value, name, err)
+			}
+		}
+	})
+	if envErr != nil {
+		return c, false, envErr
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return c, false, err
+	}
+
+	return c, printConfig, c.validate()
+}
+
+// validate reports every invalid setting at once.
+func (c Config) validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		errs = append(errs, fmt.Errorf("listen: %w", err))
+	}
+	for _, path := range []struct{ name, value string }{{"path", c.Path}, {"metricsPath", c.MetricsPath}} {
+		if !strings.HasPrefix(path.value, "/") {
+			errs = append(errs, fmt.Errorf("%s: %q must start with /", path.name, path.value))
+		}
+	}
+	if c.Path == c.MetricsPath {
+		errs = append(errs, fmt.Errorf("path and metricsPath are both %q", c.Path))
+	}
+
+	if c.ShutdownTimeout < 0 {
+		errs = append(errs, fmt.Errorf("shutdownTimeout: %s is negative", c.ShutdownTimeout))
+	}
+
+	if c.Sampling.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("sampling.interval: %s is not positive", c.Sampling.Interval))
+	}
+	if c.Sampling.Retention < c.Sampling.Interval {
+		errs = append(errs, fmt.Errorf("sampling.retention: %s is shorter than the interval", c.Sampling.Retention))
+	}
+
+	known := make(map[string]bool)
+	for _, name := range metrics.Collectors.Names() {
+		known[name] = true
+	}
+	for _, name := range c.Collectors.Disabled {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("collectors.disabled: unknown collector %q", name))
+		}
+	}
+	for _, name := range sortedKeys(c.Collectors.TTL) {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("collectors.ttl: unknown collector %q", name))
+		}
+		if _, err := parseTTL(c.Collectors.TTL[name]); err != nil {
+			errs = append(errs, fmt.Errorf("collectors.ttl.%s: %w", name, err))
+		}
+	}
+
+	for _, limit := range []struct {
+		name  string
+		value int
+	}{{"maxDepth", c.Limits.MaxDepth}, {"maxAliases", c.Limits.MaxAliases}, {"maxCost", c.Limits.MaxCost}} {
+		if limit.value < 0 {
+			errs = append(errs, fmt.Errorf("limits.%s: %d is negative", limit.name, limit.value))
+		}
+	}
+
+	errs = append(errs, c.TLS.validate()...)
+	errs = append(errs, c.Auth.validate()...)
+
+	for _, root := range []struct{ name, dir string }{{"proc", c.Roots.Proc}, {"sys", c.Roots.Sys}} {
+		if info, err := os.Stat(root.dir); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Errorf("roots.%s: %q is not a directory", root.name, root.dir))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// apply sets up the metrics package with c. It is called again when the
+// configuration is reloaded, except for the sampling and the listener that
+// main restarts or keeps.
+func (c Config) apply() {
+	metrics.SetHostFS(metrics.FS{
+		Proc: c.Roots.Proc,
+		Sys:  c.Roots.Sys,
+		Etc:  c.Roots.Etc,
+		Run:  c.Roots.Run,
+	})
+	metrics.SetCgroupRoot(c.Roots.Cgroup)
+
+	metrics.SetLimits(metrics.Limits{
+		MaxDepth:   c.Limits.MaxDepth,
+		MaxAliases: c.Limits.MaxAliases,
+		MaxCost:    c.Limits.MaxCost,
+	})
+
+	if c.authEnabled() {
+		metrics.SetAccessRules(c.Auth.Restrict)
+	} else {
+		metrics.SetAccessRules(nil)
+	}
+
+	for name, value := range c.Collectors.TTL {
+		ttl, _ := parseTTL(value)
+		metrics.Collectors.SetTTL(name, ttl)
+	}
+	disabled := make(map[string]bool)
+	for _, name := range c.Collectors.Disabled {
+		disabled[name] = true
+	}
+	for _, name := range metrics.Collectors.Names() {
+		metrics.Collectors.SetEnabled(name, !disabled[name])
+	}
+}
+
+func parseTTL(value string) (time.Duration, error) {
+	if value == "-1" {
+		return metrics.CacheForever, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func formatTTL(ttl time.Duration) string {
+	if ttl == metrics.CacheForever {
+		return "-1"
+	}
+	return ttl.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// listValue is a flag holding a comma separated list.
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// ttlValue is a flag holding comma separated name=duration pairs, added to
+// the defaults and the ones of the file.
+type ttlValue map[string]string
+
+func (t *ttlValue) String() string {
+	if t == nil {
+		return ""
+	}
+	var pairs []string
+	for _, name := range sortedKeys(*t) {
+		pairs = append(pairs, name+"="+(*t)[name])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (t *ttlValue) Set(value string) error {
+	if *t == nil {
+		*t = make(map[string]string)
+	}
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, ttl, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("%q is not a name=duration pair", pair)
+		}
+		(*t)[strings.TrimSpace(name)] = strings.TrimSpace(ttl)
+	}
+	return nil
+}
+
+// displayAddr turns a listen address into one to print, localhost standing
+// for every address.
+func displayAddr(listen string) string {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil || host != "" && host != "0.0.0.0" && host != "::" {
+		return listen
+	}
+	return net.JoinHostPort("localhost", port)
+}
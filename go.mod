@@ -4,8 +4,10 @@ go 1.22.5
 
 require (
 	github.com/elastic/go-sysinfo v1.14.1
+	github.com/gorilla/websocket v1.5.3
 	github.com/graphql-go/graphql v0.8.1
 	github.com/shirou/gopsutil v3.21.11+incompatible
+	gopkg.in/yaml.v3 v3.0.0
 )
 
 require (
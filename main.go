@@ -1,32 +1,181 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/davidjosearaujo/gometric/metrics"
-	"github.com/graphql-go/graphql"
+	"gopkg.in/yaml.v3"
 )
 
 func main() {
+	config, printConfig, err := loadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+
+	if printConfig {
+		printed := config
+		printed.Auth = config.Auth.redacted()
+		yaml.NewEncoder(os.Stdout).Encode(printed)
+		return
+	}
+
+	auth, err := newAuthenticator(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	metrics.SetWebSocketAuthenticator(auth.authenticateInit)
+	config.apply()
+	sampling := startSampler(config)
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/gometric", func(w http.ResponseWriter, r *http.Request) {
-		query := r.URL.Query().Get("query")
-		result := graphql.Do(graphql.Params{
-			Schema:        metrics.MetricsSchema,
-			RequestString: query,
-		})
-		json.NewEncoder(w).Encode(result)
-	})
+	mux.HandleFunc(config.Path, metrics.ServeGraphQL)
+	mux.HandleFunc(config.MetricsPath, metrics.ServePrometheus)
 
 	server := &http.Server{
-		Addr:    ":7000",
-		Handler: mux,
+		Addr:    config.Listen,
+		Handler: auth.authenticate(mux, config.Path),
+	}
+	server.RegisterOnShutdown(metrics.CloseWebSockets)
+
+	scheme := "http"
+	var files *tlsFiles
+	if config.TLS.enabled() {
+		scheme = "https"
+		if files, err = newTLSFiles(config.TLS); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		server.TLSConfig = files.serverConfig()
+		server.Handler = files.withClientRole(server.Handler)
 	}
 
-	fmt.Println("Gometric server running at: http://localhost:7000/gometric")
+	serveErr := make(chan error, 1)
+	go func() {
+		if files != nil {
+			serveErr <- server.ListenAndServeTLS("", "")
+		} else {
+			serveErr <- server.ListenAndServe()
+		}
+	}()
+
+	fmt.Printf("Gometric server running at: %s://%s%s\n", scheme, displayAddr(config.Listen), config.Path)
+	fmt.Printf("Prometheus metrics available at: %s://%s%s\n", scheme, displayAddr(config.Listen), config.MetricsPath)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+	for {
+		select {
+		case err := <-serveErr:
+			sampling.stop()
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				config, sampling = reload(config, sampling, files, auth)
+				continue
+			}
+
+			log.Printf("Received %s, shutting down", sig)
+			signal.Stop(signals)
+			shutdown(server, sampling, config.ShutdownTimeout)
+			return
+		}
+	}
+}
+
+// shutdown stops accepting requests, waits up to timeout for those in
+// flight and then stops the sampler.
+func shutdown(server *http.Server, sampling *sampler, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Requests still in flight after %s were cut: %v", timeout, err)
+		server.Close()
+	}
+	sampling.stop()
+}
+
+// reload reads the configuration again and applies it. Invalid changes are
+// ignored, the listener, the endpoint paths and whether TLS is used are kept
+// as they are, and the sampler is only restarted, losing its history, when
+// its settings changed.
+func reload(current Config, sampling *sampler, files *tlsFiles, auth *authenticator) (Config, *sampler) {
+	config, _, err := loadConfig(os.Args[1:])
+	if err != nil {
+		log.Printf("Configuration not reloaded:\n%v", err)
+		return current, sampling
+	}
+
+	if config.Listen != current.Listen || config.Path != current.Path || config.MetricsPath != current.MetricsPath {
+		log.Printf("The listen address and the paths only change on restart, keeping %s%s and %s",
+			current.Listen, current.Path, current.MetricsPath)
+		config.Listen, config.Path, config.MetricsPath = current.Listen, current.Path, current.MetricsPath
+	}
+
+	if config.TLS.enabled() != current.TLS.enabled() {
+		log.Printf("TLS is only turned on or off on restart")
+		config.TLS = current.TLS
+	} else if files != nil {
+		if err := files.update(config.TLS); err != nil {
+			log.Printf("Keeping the previous TLS configuration: %v", err)
+			config.TLS = current.TLS
+		}
+	}
+
+	if err := auth.update(config); err != nil {
+		log.Printf("Keeping the previous authentication settings: %v", err)
+		config.Auth = current.Auth
+		auth.update(config)
+	}
+
+	config.apply()
+	if config.Sampling != current.Sampling {
+		sampling.stop()
+		sampling = startSampler(config)
+	}
+
+	log.Printf("Configuration reloaded")
+	return config, sampling
+}
+
+// sampler runs metrics.History in the background until stopped.
+type sampler struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func startSampler(config Config) *sampler {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &sampler{cancel: cancel, done: make(chan struct{})}
+
+	metrics.History.Reconfigure(config.Sampling.Interval, config.Sampling.Retention)
+	go func() {
+		defer close(s.done)
+		metrics.History.Run(ctx)
+	}()
+
+	return s
+}
 
-	server.ListenAndServe()
+func (s *sampler) stop() {
+	s.cancel()
+	<-s.done
 }
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/graphql-go/graphql"
+)
+
+const codeForbidden = "FORBIDDEN"
+
+type roleKey struct{}
+
+// WithRole returns a context for a request made by a client with role.
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleKey{}, role)
+}
+
+// RoleFromContext returns the role of the client making the request, empty
+// when the client is anonymous.
+func RoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(roleKey{}).(string)
+	return role
+}
+
+// AccessRules restricts fields, named Type.field (e.g. Query.processes), to
+// the roles allowed to resolve them. A rule named after an object type
+// (e.g. Process) restricts every field resolving to it, whatever the path
+// the client takes. Fields missing here are open to every client.
+type AccessRules map[string][]string
+
+var accessRules atomic.Pointer[AccessRules]
+
+// SetAccessRules replaces the rules fields are resolved with, nil lifting
+// every restriction.
+func SetAccessRules(rules AccessRules) {
+	if rules == nil {
+		accessRules.Store(nil)
+		return
+	}
+	accessRules.Store(&rules)
+}
+
+// HasRuleTarget tells whether the schema has the field named Type.field,
+// or the object type called name, access rules can apply to.
+func HasRuleTarget(name string) bool {
+	typeName, fieldName, isField := strings.Cut(name, ".")
+	object, ok := MetricsSchema.Type(typeName).(*graphql.Object)
+	if !ok || strings.HasPrefix(typeName, "__") {
+		return false
+	}
+	if !isField {
+		return true
+	}
+	_, ok = object.Fields()[fieldName]
+	return ok
+}
+
+// authorize returns a FORBIDDEN error when the role of the request may not
+// resolve the field called name, of type typeName.
+func authorize(ctx context.Context, name, typeName string) error {
+	rules := accessRules.Load()
+	if rules == nil {
+		return nil
+	}
+
+	role := RoleFromContext(ctx)
+	if allowed(*rules, name, role) && allowed(*rules, typeName, role) {
+		return nil
+	}
+	if role == "" {
+		return &metricError{code: codeForbidden, err: fmt.Errorf("Anonymous clients may not read %s", name)}
+	}
+	return &metricError{code: codeForbidden, err: fmt.Errorf("Role %q may not read %s", role, name)}
+}
+
+// allowed tells whether the rule called name, if any, lets role through.
+func allowed(rules AccessRules, name, role string) bool {
+	roles, ok := rules[name]
+	if !ok {
+		return true
+	}
+	for _, allowed := range roles {
+		if role != "" && role == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeResolvers makes every resolver of schema check the access rules
+// before resolving its field. The rules are read on every call, so they can
+// change while the server runs.
+func authorizeResolvers(schema graphql.Schema) {
+	for name, t := range schema.TypeMap() {
+		object, ok := t.(*graphql.Object)
+		if !ok || len(name) > 1 && name[:2] == "__" {
+			continue
+		}
+
+		for _, field := range object.Fields() {
+			resolve := field.Resolve
+			if resolve == nil {
+				resolve = graphql.DefaultResolveFn
+			}
+			name, typeName := object.Name()+"."+field.Name, graphql.GetNamed(field.Type).String()
+			field.Resolve = authorizeResolve(name, typeName, resolve)
+			if field.Subscribe != nil {
+				field.Subscribe = authorizeResolve(name, typeName, field.Subscribe)
+			}
+		}
+	}
+}
+
+func authorizeResolve(name, typeName string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if err := authorize(p.Context, name, typeName); err != nil {
+			return nil, err
+		}
+		return resolve(p)
+	}
+}
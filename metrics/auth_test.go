@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// defaultRules restricts processes and sockets like the server does when
+// its configuration has no rules.
+var defaultRules = AccessRules{
+	"Process":             {"admin"},
+	"Network.connections": {"admin"},
+	"Network.listeners":   {"admin"},
+	"Host.macs":           {"admin"},
+}
+
+// TestProcessesForbidden checks that a role left out of the rules on
+// processes can not read them through any of the fields leading to them.
+func TestProcessesForbidden(t *testing.T) {
+	useFixtureHost(t)
+	t.Cleanup(func() { SetAccessRules(nil) })
+
+	queries := map[string]string{
+		"Query.process":       `{ process(pid: 1) { name cmdline user } }`,
+		"Query.processes":     `{ processes { name cmdline user } }`,
+		"Network.connections": `{ network { connections { process { name cmdline user } } } }`,
+		"Network.listeners":   `{ network { listeners { process { name cmdline user } } } }`,
+	}
+
+	for _, rules := range []struct {
+		name  string
+		rules AccessRules
+	}{
+		{"default", defaultRules},
+		// Sockets open to everyone, the processes behind them still not
+		{"processes only", AccessRules{"Process": {"admin"}}},
+	} {
+		SetAccessRules(rules.rules)
+
+		for path, query := range queries {
+			t.Run(rules.name+"/"+path, func(t *testing.T) {
+				result := graphql.Do(graphql.Params{
+					Schema:        MetricsSchema,
+					RequestString: query,
+					Context:       WithRole(context.Background(), "viewer"),
+				})
+
+				data, _ := json.Marshal(result.Data)
+				for _, detail := range []string{`"name"`, `"cmdline"`, `"user"`} {
+					if strings.Contains(string(data), detail) {
+						t.Errorf("viewer read %s: %s", detail, data)
+					}
+				}
+				if len(result.Errors) == 0 {
+					t.Errorf("viewer got no FORBIDDEN error: %s", data)
+				}
+				for _, err := range result.Errors {
+					if err.Extensions["code"] != "FORBIDDEN" {
+						t.Errorf("error %q has code %v, want FORBIDDEN", err.Message, err.Extensions["code"])
+					}
+				}
+			})
+		}
+	}
+}
+
+// TestProcessFieldsForbidden checks every field of the schema resolving to
+// processes, including the ones added after the rules were written.
+func TestProcessFieldsForbidden(t *testing.T) {
+	SetAccessRules(defaultRules)
+	t.Cleanup(func() { SetAccessRules(nil) })
+
+	found := 0
+	for name, typ := range MetricsSchema.TypeMap() {
+		object, ok := typ.(*graphql.Object)
+		if !ok || strings.HasPrefix(name, "__") {
+			continue
+		}
+		for _, field := range object.Fields() {
+			if graphql.GetNamed(field.Type).String() != "Process" {
+				continue
+			}
+			found++
+
+			_, err := field.Resolve(graphql.ResolveParams{
+				Context: WithRole(context.Background(), "viewer"),
+				Args:    map[string]interface{}{},
+			})
+			extended, ok := err.(gqlerrors.ExtendedError)
+			if !ok || extended.Extensions()["code"] != "FORBIDDEN" {
+				t.Errorf("%s.%s resolved for viewer, error %v", name, field.Name, err)
+			}
+		}
+	}
+
+	if found < 3 {
+		t.Fatalf("found %d fields resolving to processes, want at least 3", found)
+	}
+}
+
+func TestProcessesAllowedForAdmin(t *testing.T) {
+	useFixtureHost(t)
+	SetAccessRules(defaultRules)
+	t.Cleanup(func() { SetAccessRules(nil) })
+
+	result := graphql.Do(graphql.Params{
+		Schema:        MetricsSchema,
+		RequestString: `{ process(pid: 1) { name } }`,
+		Context:       WithRole(context.Background(), "admin"),
+	})
+	if result.HasErrors() {
+		t.Fatalf("admin got %v", result.Errors)
+	}
+	data, _ := json.Marshal(result.Data)
+	if string(data) != `{"process":{"name":"init"}}` {
+		t.Fatalf("admin got %s", data)
+	}
+}
@@ -0,0 +1,179 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheForever is the TTL of snapshots that never change, like the model of
+// the CPUs.
+const CacheForever time.Duration = -1
+
+// DefaultTTLs is how long the snapshots of the built-in collectors are
+// reused. Collectors missing here are only cached for a single request.
+var DefaultTTLs = map[string]time.Duration{
+	"host":        time.Minute,
+	"cpu":         time.Second,
+	"cpuinfo":     CacheForever,
+	"memory":      time.Second,
+	"network":     time.Second,
+	"interfaces":  time.Second,
+	"connections": 2 * time.Second,
+	"disk":        5 * time.Second,
+	"partitions":  5 * time.Second,
+	"diskio":      time.Second,
+	"processes":   2 * time.Second,
+	"pressure":    time.Second,
+	"cgroups":     time.Second,
+}
+
+// CacheStats counts how often the snapshot of a collector was reused or had
+// to be collected.
+type CacheStats struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func (s *CacheStats) Hits() uint64 {
+	return s.hits.Load()
+}
+
+func (s *CacheStats) Misses() uint64 {
+	return s.misses.Load()
+}
+
+type cacheEntry struct {
+	snapshot Snapshot
+	stored   time.Time
+	expires  time.Time // Zero when cached forever
+}
+
+// requestCache holds the snapshots collected while answering a single
+// request, so that fields reading the same collector share one snapshot.
+type requestCache struct {
+	mu        sync.Mutex
+	snapshots map[string]Snapshot
+}
+
+type requestCacheKey struct{}
+
+// WithRequestCache returns a context in which Collect runs each collector at
+// most once, whatever its TTL.
+func WithRequestCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestCacheKey{}, &requestCache{snapshots: make(map[string]Snapshot)})
+}
+
+type maxAgeKey struct{}
+
+// withMaxAge returns a context in which Collect ignores snapshots older than
+// the age set with setMaxAge, whatever their TTL.
+func withMaxAge(ctx context.Context) context.Context {
+	return context.WithValue(ctx, maxAgeKey{}, new(atomic.Int64))
+}
+
+// setMaxAge sets the age of the snapshots Collect may reuse with ctx. It
+// does nothing when ctx does not come from withMaxAge.
+func setMaxAge(ctx context.Context, age time.Duration) {
+	if maxAge, ok := ctx.Value(maxAgeKey{}).(*atomic.Int64); ok {
+		maxAge.Store(int64(age))
+	}
+}
+
+// tooOld tells whether a snapshot stored at stored is older than what ctx
+// accepts.
+func tooOld(ctx context.Context, stored time.Time) bool {
+	maxAge, ok := ctx.Value(maxAgeKey{}).(*atomic.Int64)
+	return ok && maxAge.Load() > 0 && time.Since(stored) >= time.Duration(maxAge.Load())
+}
+
+// SetTTL sets how long the snapshot of the collector called name is reused,
+// 0 to collect it on every request and CacheForever to collect it once.
+func (r *Registry) SetTTL(name string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.collectors[name]; !ok {
+		return fmt.Errorf("unknown collector %q", name)
+	}
+	r.ttls[name] = ttl
+	delete(r.cache, name)
+	return nil
+}
+
+// CacheStats returns the cache counters of the collector called name, nil
+// when there is no such collector.
+func (r *Registry) CacheStats(name string) *CacheStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.stats[name]
+}
+
+// cached returns the snapshot of the collector called name cached for the
+// request of ctx or, when fresh enough, for the registry.
+func (r *Registry) cached(ctx context.Context, name string) (Snapshot, bool) {
+	rc, _ := ctx.Value(requestCacheKey{}).(*requestCache)
+	if rc != nil {
+		rc.mu.Lock()
+		snapshot, ok := rc.snapshots[name]
+		rc.mu.Unlock()
+		if ok {
+			r.CacheStats(name).hits.Add(1)
+			return snapshot, true
+		}
+	}
+
+	r.mu.RLock()
+	entry, ok := r.cache[name]
+	stats := r.stats[name]
+	r.mu.RUnlock()
+
+	if !ok || !entry.expires.IsZero() && time.Now().After(entry.expires) || tooOld(ctx, entry.stored) {
+		return nil, false
+	}
+
+	stats.hits.Add(1)
+	storeRequest(ctx, name, entry.snapshot)
+	return entry.snapshot, true
+}
+
+// last returns the snapshot of the collector called name cached last, even
+// when expired, without collecting it.
+func (r *Registry) last(name string) (Snapshot, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.cache[name]
+	return entry.snapshot, ok
+}
+
+// store caches the snapshot of the collector called name for the request of
+// ctx and for the registry.
+func (r *Registry) store(ctx context.Context, name string, snapshot Snapshot) {
+	storeRequest(ctx, name, snapshot)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	ttl := r.ttls[name]
+	switch {
+	case ttl == CacheForever:
+		r.cache[name] = cacheEntry{snapshot: snapshot, stored: now}
+	case ttl > 0:
+		r.cache[name] = cacheEntry{snapshot: snapshot, stored: now, expires: now.Add(ttl)}
+	}
+}
+
+// storeRequest caches the snapshot of the collector called name for the
+// request of ctx only.
+func storeRequest(ctx context.Context, name string, snapshot Snapshot) {
+	if rc, ok := ctx.Value(requestCacheKey{}).(*requestCache); ok {
+		rc.mu.Lock()
+		rc.snapshots[name] = snapshot
+		rc.mu.Unlock()
+	}
+}
@@ -0,0 +1,305 @@
+package metrics
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cgroupRoot is where the cgroup v2 hierarchy is mounted, it is looked for
+// in the fs/cgroup directory of HostFS().Sys when empty.
+var cgroupRoot atomic.Pointer[string]
+
+// SetCgroupRoot changes where the cgroup v2 hierarchy is read from, empty
+// to look for it in sysfs.
+func SetCgroupRoot(root string) {
+	cgroupRoot.Store(&root)
+}
+
+// Cgroup is the resource usage of a cgroup v2. The parts of controllers that
+// are not enabled for the cgroup are nil.
+type Cgroup struct {
+	Path     string
+	CPU      *CgroupCPU
+	Memory   *CgroupMemory
+	IO       []CgroupIO
+	Pids     *CgroupPids
+	Children []Cgroup
+}
+
+type CgroupCPU struct {
+	Usage            time.Duration
+	User             time.Duration
+	System           time.Duration
+	Periods          uint64
+	ThrottledPeriods uint64
+	ThrottledTime    time.Duration
+	Quota            *time.Duration // Nil when unlimited
+	Period           *time.Duration
+}
+
+type CgroupMemory struct {
+	Current uint64
+	Max     *uint64 // Nil when unlimited
+	Events  map[string]uint64
+}
+
+type CgroupIO struct {
+	Device     string
+	ReadBytes  uint64
+	WriteBytes uint64
+	Reads      uint64
+	Writes     uint64
+}
+
+type CgroupPids struct {
+	Current uint64
+	Max     *uint64 // Nil when unlimited
+}
+
+// cgroup2Root returns where the cgroup v2 hierarchy is mounted, the root
+// given to SetCgroupRoot when set or else either sys/fs/cgroup or, on
+// hybrid hosts, sys/fs/cgroup/unified.
+func cgroup2Root() (string, error) {
+	fs := HostFS()
+	roots := []string{fs.sys("fs", "cgroup"), fs.sys("fs", "cgroup", "unified")}
+	if root := cgroupRoot.Load(); root != nil && *root != "" {
+		roots = []string{*root}
+	}
+
+	for _, root := range roots {
+		if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+			return root, nil
+		}
+	}
+	return "", unavailableError("CGROUP2_UNAVAILABLE", "No cgroup v2 hierarchy is mounted")
+}
+
+// cgroupTree reads the cgroups of a cgroup v2 hierarchy the first time they
+// are asked for, and returns the same ones after.
+type cgroupTree struct {
+	root string
+
+	mu   sync.Mutex
+	read map[cgroupRequest]Cgroup
+}
+
+type cgroupRequest struct {
+	path      string
+	recursive bool
+}
+
+// collectCgroups finds the cgroup v2 hierarchy, read by find.
+func collectCgroups() (*cgroupTree, error) {
+	root, err := cgroup2Root()
+	if err != nil {
+		return nil, err
+	}
+	return &cgroupTree{root: root, read: make(map[cgroupRequest]Cgroup)}, nil
+}
+
+// find returns the cgroup at path, with the cgroups below it when recursive.
+// Only that part of the hierarchy is read.
+func (t *cgroupTree) find(path string, recursive bool) (Cgroup, error) {
+	request := cgroupRequest{filepath.Clean("/" + path), recursive}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if cgroup, ok := t.read[request]; ok {
+		return cgroup, nil
+	}
+	if info, err := os.Stat(filepath.Join(t.root, request.path)); err != nil || !info.IsDir() {
+		return Cgroup{}, notFoundError("cgroup %q not found", request.path)
+	}
+
+	cgroup := readCgroup(t.root, request.path, recursive)
+	t.read[request] = cgroup
+	return cgroup, nil
+}
+
+// readCgroup reads the cgroup at path below root, and the ones below it
+// when recursive.
+func readCgroup(root, path string, recursive bool) Cgroup {
+	dir := filepath.Join(root, path)
+	cgroup := Cgroup{
+		Path:     path,
+		CPU:      readCgroupCPU(dir),
+		Memory:   readCgroupMemory(dir),
+		IO:       readCgroupIO(dir),
+		Pids:     readCgroupPids(dir),
+		Children: []Cgroup{},
+	}
+	if !recursive {
+		return cgroup
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return cgroup
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			cgroup.Children = append(cgroup.Children, readCgroup(root, filepath.Join(path, entry.Name()), true))
+		}
+	}
+	sort.Slice(cgroup.Children, func(i, j int) bool {
+		return cgroup.Children[i].Path < cgroup.Children[j].Path
+	})
+
+	return cgroup
+}
+
+func readCgroupCPU(dir string) *CgroupCPU {
+	stat, err := readFlatKeyed(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return nil
+	}
+
+	usec := func(key string) time.Duration {
+		return time.Duration(stat[key]) * time.Microsecond
+	}
+	cpu := &CgroupCPU{
+		Usage:            usec("usage_usec"),
+		User:             usec("user_usec"),
+		System:           usec("system_usec"),
+		Periods:          stat["nr_periods"],
+		ThrottledPeriods: stat["nr_throttled"],
+		ThrottledTime:    usec("throttled_usec"),
+	}
+
+	// $MAX $PERIOD, cpu.max only exists when the cpu controller is enabled
+	if fields := strings.Fields(readCgroupFile(filepath.Join(dir, "cpu.max"))); len(fields) == 2 {
+		if period, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			p := time.Duration(period) * time.Microsecond
+			cpu.Period = &p
+		}
+		if quota, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			q := time.Duration(quota) * time.Microsecond
+			cpu.Quota = &q
+		}
+	}
+
+	return cpu
+}
+
+func readCgroupMemory(dir string) *CgroupMemory {
+	current, err := strconv.ParseUint(readCgroupFile(filepath.Join(dir, "memory.current")), 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	memory := &CgroupMemory{
+		Current: current,
+		Max:     readCgroupLimit(filepath.Join(dir, "memory.max")),
+	}
+	memory.Events, _ = readFlatKeyed(filepath.Join(dir, "memory.events"))
+
+	return memory
+}
+
+func readCgroupIO(dir string) []CgroupIO {
+	file, err := os.Open(filepath.Join(dir, "io.stat"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	ios := []CgroupIO{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		io := CgroupIO{Device: blockDeviceName(fields[0])}
+		for _, field := range fields[1:] {
+			key, value, _ := strings.Cut(field, "=")
+			n, _ := strconv.ParseUint(value, 10, 64)
+			switch key {
+			case "rbytes":
+				io.ReadBytes = n
+			case "wbytes":
+				io.WriteBytes = n
+			case "rios":
+				io.Reads = n
+			case "wios":
+				io.Writes = n
+			}
+		}
+		ios = append(ios, io)
+	}
+
+	return ios
+}
+
+func readCgroupPids(dir string) *CgroupPids {
+	current, err := strconv.ParseUint(readCgroupFile(filepath.Join(dir, "pids.current")), 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	return &CgroupPids{
+		Current: current,
+		Max:     readCgroupLimit(filepath.Join(dir, "pids.max")),
+	}
+}
+
+// blockDeviceName turns the major:minor number of a block device into its
+// name, or leaves it as is when the device is unknown.
+func blockDeviceName(number string) string {
+	target, err := os.Readlink(HostFS().sys("dev", "block", number))
+	if err != nil {
+		return number
+	}
+	return filepath.Base(target)
+}
+
+func readCgroupFile(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// readCgroupLimit reads a file holding either a number or max, for which
+// nil is returned.
+func readCgroupLimit(path string) *uint64 {
+	limit, err := strconv.ParseUint(readCgroupFile(path), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &limit
+}
+
+// readFlatKeyed reads a file of "key value" lines, like cpu.stat.
+func readFlatKeyed(path string) (map[string]uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = value
+		}
+	}
+
+	return values, scanner.Err()
+}
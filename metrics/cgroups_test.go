@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// cgroupPaths returns the path of c and of every cgroup below it.
+func cgroupPaths(c Cgroup) []string {
+	paths := []string{c.Path}
+	for _, child := range c.Children {
+		paths = append(paths, cgroupPaths(child)...)
+	}
+	return paths
+}
+
+func TestCgroupTreeFind(t *testing.T) {
+	useFixtureHost(t)
+
+	tests := []struct {
+		name      string
+		path      string
+		recursive bool
+		want      []string // Nil when the cgroup is not found
+	}{
+		{"root", "/", false, []string{"/"}},
+		{"root recursive", "/", true, []string{"/", "/system.slice", "/system.slice/ssh.service"}},
+		{"subtree", "system.slice", true, []string{"/system.slice", "/system.slice/ssh.service"}},
+		{"leaf", "/system.slice/ssh.service", false, []string{"/system.slice/ssh.service"}},
+		{"parent path", "/system.slice/../../system.slice", false, []string{"/system.slice"}},
+		{"missing", "/user.slice", false, nil},
+		{"file", "/cgroup.controllers", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := collectCgroups()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cgroup, err := tree.find(tt.path, tt.recursive)
+			if tt.want == nil {
+				var metricErr *metricError
+				if !errors.As(err, &metricErr) || metricErr.Extensions()["code"] != codeNotFound {
+					t.Fatalf("find() = %v, want a not found error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cgroupPaths(cgroup); !slices.Equal(got, tt.want) {
+				t.Errorf("find() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
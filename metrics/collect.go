@@ -0,0 +1,341 @@
+package metrics
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/elastic/go-sysinfo/types"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/net"
+)
+
+// collectHost returns the host info, including its OS.
+func collectHost() (types.HostInfo, error) {
+	host, err := getHost()
+	if err != nil {
+		return types.HostInfo{}, err
+	}
+	return host.Info(), nil
+}
+
+// collectCPU gathers the CPU load and timers, as a whole and per logical
+// CPU. The CPU info is left out as it never changes and is expensive to read.
+func collectCPU() (CPU, error) {
+	var cpuObj CPU
+
+	host, err := getHost()
+	if err != nil {
+		return cpuObj, err
+	}
+
+	// CPU Load
+	if load, ok := host.(types.LoadAverage); ok {
+		if cpuObj.Load, err = load.LoadAverage(); err != nil {
+			return cpuObj, err
+		}
+	}
+
+	// CPU timers
+	if cpuObj.Time, err = host.CPUTime(); err != nil {
+		return cpuObj, err
+	}
+
+	// CPU Number of cores
+	cpuObj.CoreCount = int16(runtime.NumCPU())
+
+	// Logical CPUs
+	if cpuObj.Cores, err = collectCores(); err != nil {
+		return cpuObj, err
+	}
+
+	return cpuObj, nil
+}
+
+func collectMemory() (Memory, error) {
+	var memObj Memory
+
+	host, err := getHost()
+	if err != nil {
+		return memObj, err
+	}
+
+	memory, err := host.Memory()
+	if err != nil {
+		return memObj, err
+	}
+	memObj.HostMemoryInfo = *memory
+
+	if vmstat, ok := host.(types.VMStat); ok {
+		info, err := vmstat.VMStat()
+		if err != nil {
+			return memObj, err
+		}
+		memObj.VMStat = vmstatCounters(info)
+	}
+
+	return memObj, nil
+}
+
+// vmstatCounters turns the counters of /proc/vmstat into a map keyed by
+// their name in the file.
+func vmstatCounters(info *types.VMStatInfo) map[string]uint64 {
+	counters := make(map[string]uint64)
+	value := reflect.ValueOf(*info)
+	for i := 0; i < value.NumField(); i++ {
+		name, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			counters[name] = value.Field(i).Uint()
+		}
+	}
+	return counters
+}
+
+func collectNetwork() (Network, error) {
+	var network Network
+
+	host, err := getHost()
+	if err != nil {
+		return network, err
+	}
+
+	if n, ok := host.(types.NetworkCounters); ok {
+		netcounter, err := n.NetworkCounters()
+		if err != nil {
+			return network, err
+		}
+		network.Network = *netcounter
+	}
+
+	return network, nil
+}
+
+// collectDisk lists the partitions, without their usage.
+func collectDisk() (Disk, error) {
+	partitions, err := disk.Partitions(false)
+	if err != nil {
+		return Disk{}, err
+	}
+	return Disk{Partitions: partitions}, nil
+}
+
+// narrow narrows the partitions of d down to device and reads its usage,
+// d is left as is when device is empty.
+func (d Disk) narrow(device string) (Disk, error) {
+	if device == "" {
+		return d, nil
+	}
+
+	for _, partition := range d.Partitions {
+		if partition.Device == device {
+			diskObj := Disk{Device: device, Partitions: []disk.PartitionStat{partition}}
+			if usage, err := HostFS().usage(partition.Mountpoint); err == nil {
+				diskObj.UsageStat = *usage
+			} else {
+				diskObj.usageErr = collectorError("disk", err)
+			}
+			return diskObj, nil
+		}
+	}
+
+	return Disk{}, notFoundError("device %q not found", device)
+}
+
+// partitionTable lists the mounted partitions, the virtual ones included,
+// and reads the usage of each the first time it is asked for.
+type partitionTable struct {
+	partitions []Partition
+
+	mu    sync.Mutex
+	usage map[string]partitionUsage // By mountpoint
+}
+
+type partitionUsage struct {
+	stat disk.UsageStat
+	err  error
+}
+
+// collectPartitions lists every mounted partition, without its usage.
+func collectPartitions() (*partitionTable, error) {
+	all, err := disk.Partitions(true)
+	if err != nil {
+		return nil, err
+	}
+	physical, err := disk.Partitions(false)
+	if err != nil {
+		return nil, err
+	}
+
+	backed := make(map[disk.PartitionStat]bool, len(physical))
+	for _, partition := range physical {
+		backed[partition] = true
+	}
+
+	partitions := make([]Partition, 0, len(all))
+	for _, partition := range all {
+		partitions = append(partitions, Partition{PartitionStat: partition, Virtual: !backed[partition]})
+	}
+
+	return &partitionTable{partitions: partitions, usage: make(map[string]partitionUsage)}, nil
+}
+
+// withUsage returns partitions with their usage, or the error reading it,
+// only reading the usage of the ones not read before.
+func (t *partitionTable) withUsage(ctx context.Context, partitions []Partition) ([]Partition, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	withUsage := make([]Partition, 0, len(partitions))
+	for _, partition := range partitions {
+		usage, ok := t.usage[partition.Mountpoint]
+		if !ok {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if stat, err := HostFS().usage(partition.Mountpoint); err == nil {
+				usage.stat = *stat
+			} else {
+				usage.err = collectorError("partitions", err)
+			}
+			t.usage[partition.Mountpoint] = usage
+		}
+		partition.UsageStat, partition.usageErr = usage.stat, usage.err
+		withUsage = append(withUsage, partition)
+	}
+	return withUsage, nil
+}
+
+// selectPartitions keeps the partitions of type fstype mounted under
+// mountpointPrefix when given.
+func (t *partitionTable) selectPartitions(fstype, mountpointPrefix string, includeVirtual bool) []Partition {
+	list := []Partition{}
+	for _, partition := range t.partitions {
+		if partition.Virtual && !includeVirtual {
+			continue
+		}
+		if fstype != "" && partition.Fstype != fstype {
+			continue
+		}
+		if !strings.HasPrefix(partition.Mountpoint, mountpointPrefix) {
+			continue
+		}
+		list = append(list, partition)
+	}
+	return list
+}
+
+// collectDiskIO returns the IO counters of every device, sorted by name.
+func collectDiskIO() ([]disk.IOCountersStat, error) {
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return nil, err
+	}
+
+	io := make([]disk.IOCountersStat, 0, len(counters))
+	for _, counter := range counters {
+		io = append(io, counter)
+	}
+	sort.Slice(io, func(i, j int) bool {
+		return io[i].Name < io[j].Name
+	})
+
+	return io, nil
+}
+
+// selectDiskIO keeps the counters of device when given, named with or
+// without the /dev/ prefix.
+func selectDiskIO(all []disk.IOCountersStat, device string) []disk.IOCountersStat {
+	if device == "" {
+		return all
+	}
+
+	io := []disk.IOCountersStat{}
+	for _, counter := range all {
+		if counter.Name == strings.TrimPrefix(device, "/dev/") {
+			io = append(io, counter)
+		}
+	}
+	return io
+}
+
+// collectInterfaces lists the interfaces of procfs, with their MTU, address
+// and link state read from sysfs. Flags and IP addresses only come from the
+// kernel, so they are left empty for interfaces the server can not see.
+func collectInterfaces() ([]Interface, error) {
+	counters, err := net.IOCounters(true)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	statsByName := make(map[string]net.InterfaceStat, len(stats))
+	for _, stat := range stats {
+		statsByName[stat.Name] = stat
+	}
+
+	interfaces := []Interface{}
+	for _, counter := range counters {
+		stat := statsByName[counter.Name]
+		iface := Interface{
+			Name:         counter.Name,
+			MTU:          stat.MTU,
+			HardwareAddr: stat.HardwareAddr,
+			Flags:        stat.Flags,
+			Counters:     counter,
+		}
+		for _, addr := range stat.Addrs {
+			iface.Addrs = append(iface.Addrs, addr.Addr)
+		}
+
+		if mtu, err := readSysClassNet(counter.Name, "mtu"); err == nil {
+			iface.MTU, _ = strconv.Atoi(mtu)
+		}
+		if address, err := readSysClassNet(counter.Name, "address"); err == nil {
+			// Loopback and tunnels only have zeros, reported as no address
+			if strings.Trim(address, "0:") == "" {
+				address = ""
+			}
+			iface.HardwareAddr = address
+		}
+		iface.OperState, _ = readSysClassNet(counter.Name, "operstate")
+		if speed, err := readSysClassNet(counter.Name, "speed"); err == nil {
+			// Virtual interfaces and links that are down report -1
+			if mbps, err := strconv.Atoi(speed); err == nil && mbps >= 0 {
+				iface.Speed = &mbps
+			}
+		}
+
+		interfaces = append(interfaces, iface)
+	}
+
+	return interfaces, nil
+}
+
+// selectInterfaces keeps the interface called name when given.
+func selectInterfaces(all []Interface, name string) ([]Interface, error) {
+	if name == "" {
+		return all, nil
+	}
+	for _, iface := range all {
+		if iface.Name == name {
+			return []Interface{iface}, nil
+		}
+	}
+	return nil, notFoundError("interface %q not found", name)
+}
+
+func readSysClassNet(iface, attribute string) (string, error) {
+	content, err := os.ReadFile(HostFS().sys("class", "net", iface, attribute))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
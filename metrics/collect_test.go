@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/shirou/gopsutil/disk"
+)
+
+func TestPartitionTableWithUsage(t *testing.T) {
+	read := Partition{PartitionStat: disk.PartitionStat{Device: "/dev/read", Mountpoint: t.TempDir()}}
+	unread := Partition{PartitionStat: disk.PartitionStat{Device: "/dev/unread", Mountpoint: t.TempDir()}}
+	missing := Partition{PartitionStat: disk.PartitionStat{Device: "/dev/missing", Mountpoint: filepath.Join(t.TempDir(), "missing")}}
+	table := &partitionTable{partitions: []Partition{read, unread, missing}, usage: make(map[string]partitionUsage)}
+
+	partitions, err := table.withUsage(context.Background(), []Partition{read, missing})
+	if err != nil || len(partitions) != 2 {
+		t.Fatalf("withUsage() = %+v, %v, want 2 partitions", partitions, err)
+	}
+	if partitions[0].UsageStat.Total == 0 || partitions[0].usageErr != nil {
+		t.Errorf("withUsage() = %+v, want the usage of %s", partitions[0], read.Mountpoint)
+	}
+	if partitions[1].usageErr == nil {
+		t.Errorf("withUsage() = %+v, want an error for %s", partitions[1], missing.Mountpoint)
+	}
+	if len(table.usage) != 2 {
+		t.Errorf("withUsage() read the usage of %d partitions, want 2", len(table.usage))
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if partitions, err := table.withUsage(canceled, []Partition{read}); err != nil || len(partitions) != 1 {
+		t.Errorf("withUsage() = %+v, %v, want the usage read before", partitions, err)
+	}
+	if _, err := table.withUsage(canceled, []Partition{read, unread}); err == nil {
+		t.Error("withUsage() read a usage after the request was canceled")
+	}
+}
+
+func TestCollectCoresError(t *testing.T) {
+	fs := HostFS()
+	SetHostFS(FS{Proc: t.TempDir(), Sys: fs.Sys, Etc: fs.Etc, Run: fs.Run})
+	t.Cleanup(func() { SetHostFS(fs) })
+
+	if cores, err := collectCores(); err == nil {
+		t.Errorf("collectCores() = %+v without a stat file, want an error", cores)
+	}
+}
@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/cpu"
+)
+
+// Snapshot is what a collector gathered, the value the GraphQL type of the
+// subsystem is resolved on.
+type Snapshot interface{}
+
+// Collector gathers the metrics of one subsystem.
+type Collector interface {
+	Name() string
+	Collect(ctx context.Context) (Snapshot, error)
+}
+
+type collectorFunc struct {
+	name    string
+	collect func() (Snapshot, error)
+}
+
+func (c collectorFunc) Name() string {
+	return c.name
+}
+
+func (c collectorFunc) Collect(ctx context.Context) (Snapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.collect()
+}
+
+// Registry holds the collectors queried by the GraphQL API, the Prometheus
+// endpoint and the sampler. Collectors are enabled once registered, and
+// their snapshots are cached for the TTL set with SetTTL. A collector runs
+// once for the requests missing its snapshot at the same time.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]Collector
+	disabled   map[string]bool
+	ttls       map[string]time.Duration
+	cache      map[string]cacheEntry
+	stats      map[string]*CacheStats
+	running    map[string]*run
+}
+
+// run is a collector running for every request waiting for its snapshot.
+type run struct {
+	done     chan struct{}
+	snapshot Snapshot
+	err      error
+}
+
+// Collectors is the registry every endpoint reads from.
+var Collectors = newDefaultRegistry()
+
+// newDefaultRegistry registers the built-in collectors, cached for their
+// DefaultTTLs.
+func newDefaultRegistry() *Registry {
+	r := NewRegistry(
+		collectorFunc{"host", func() (Snapshot, error) { return collectHost() }},
+		collectorFunc{"cpu", func() (Snapshot, error) { return collectCPU() }},
+		collectorFunc{"cpuinfo", func() (Snapshot, error) { return cpu.Info() }},
+		collectorFunc{"memory", func() (Snapshot, error) { return collectMemory() }},
+		collectorFunc{"network", func() (Snapshot, error) { return collectNetwork() }},
+		collectorFunc{"interfaces", func() (Snapshot, error) { return collectInterfaces() }},
+		collectorFunc{"connections", func() (Snapshot, error) { return collectConnections() }},
+		collectorFunc{"disk", func() (Snapshot, error) { return collectDisk() }},
+		collectorFunc{"partitions", func() (Snapshot, error) { return collectPartitions() }},
+		collectorFunc{"diskio", func() (Snapshot, error) { return collectDiskIO() }},
+		collectorFunc{"processes", func() (Snapshot, error) { return collectProcesses() }},
+		collectorFunc{"pressure", func() (Snapshot, error) { return systemPressure() }},
+		collectorFunc{"cgroups", func() (Snapshot, error) { return collectCgroups() }},
+	)
+	for name, ttl := range DefaultTTLs {
+		r.SetTTL(name, ttl)
+	}
+	return r
+}
+
+func NewRegistry(collectors ...Collector) *Registry {
+	r := &Registry{
+		collectors: make(map[string]Collector),
+		disabled:   make(map[string]bool),
+		ttls:       make(map[string]time.Duration),
+		cache:      make(map[string]cacheEntry),
+		stats:      make(map[string]*CacheStats),
+		running:    make(map[string]*run),
+	}
+	for _, c := range collectors {
+		r.Register(c)
+	}
+	return r
+}
+
+// Register adds c to the registry, replacing the collector of the same name
+// and dropping its cached snapshot.
+func (r *Registry) Register(c Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors[c.Name()] = c
+	delete(r.cache, c.Name())
+	if r.stats[c.Name()] == nil {
+		r.stats[c.Name()] = &CacheStats{}
+	}
+}
+
+// Names returns the name of every registered collector, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetEnabled enables or disables the collector called name.
+func (r *Registry) SetEnabled(name string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.collectors[name]; !ok {
+		return fmt.Errorf("unknown collector %q", name)
+	}
+	r.disabled[name] = !enabled
+	return nil
+}
+
+// Enabled tells whether the collector called name is registered and enabled.
+func (r *Registry) Enabled(name string) bool {
+	return r.check(name) == nil
+}
+
+// Collect returns the snapshot of the collector called name, from the cache
+// of the request or of the registry when fresh enough.
+func (r *Registry) Collect(ctx context.Context, name string) (Snapshot, error) {
+	if err := r.check(name); err != nil {
+		return nil, err
+	}
+
+	if snapshot, ok := r.cached(ctx, name); ok {
+		return snapshot, nil
+	}
+	return r.refresh(ctx, name)
+}
+
+// Refresh runs the collector called name, whatever is cached, and caches its
+// snapshot.
+func (r *Registry) Refresh(ctx context.Context, name string) (Snapshot, error) {
+	if err := r.check(name); err != nil {
+		return nil, err
+	}
+	return r.refresh(ctx, name)
+}
+
+// refresh runs the collector called name, or waits for the run already in
+// progress.
+func (r *Registry) refresh(ctx context.Context, name string) (Snapshot, error) {
+	r.mu.Lock()
+	c := r.collectors[name]
+	stats := r.stats[name]
+	current, waiting := r.running[name]
+	if !waiting {
+		current = &run{done: make(chan struct{})}
+		r.running[name] = current
+	}
+	r.mu.Unlock()
+
+	if waiting {
+		select {
+		case <-current.done:
+		case <-ctx.Done():
+			return nil, collectorError(name, ctx.Err())
+		}
+		if current.err != nil {
+			return nil, current.err
+		}
+		stats.hits.Add(1)
+		storeRequest(ctx, name, current.snapshot)
+		return current.snapshot, nil
+	}
+
+	stats.misses.Add(1)
+	// The requests waiting for the snapshot must not fail with this one
+	snapshot, err := c.Collect(context.WithoutCancel(ctx))
+	if err != nil {
+		current.err = collectorError(name, err)
+	} else {
+		current.snapshot = snapshot
+		r.store(ctx, name, snapshot)
+	}
+
+	r.mu.Lock()
+	delete(r.running, name)
+	r.mu.Unlock()
+	close(current.done)
+
+	return current.snapshot, current.err
+}
+
+// check returns an error when the collector called name can not be run.
+// Fields taking arguments call it before reading their subsystem directly.
+func (r *Registry) check(name string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.collectors[name]; !ok {
+		return notFoundError("unknown collector %q", name)
+	}
+	if r.disabled[name] {
+		return &metricError{
+			code:      "COLLECTOR_DISABLED",
+			collector: name,
+			err:       fmt.Errorf("The %s collector is disabled", name),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingCollector counts its runs, each taking delay.
+type countingCollector struct {
+	runs  atomic.Int64
+	delay time.Duration
+}
+
+func (c *countingCollector) Name() string {
+	return "counting"
+}
+
+func (c *countingCollector) Collect(ctx context.Context) (Snapshot, error) {
+	time.Sleep(c.delay)
+	return c.runs.Add(1), nil
+}
+
+func TestRegistryCollectOnce(t *testing.T) {
+	c := &countingCollector{delay: 50 * time.Millisecond}
+	r := NewRegistry(c)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if snapshot, err := r.Collect(context.Background(), c.Name()); err != nil || snapshot != int64(1) {
+				t.Errorf("Collect() = %v, %v, want 1", snapshot, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if runs := c.runs.Load(); runs != 1 {
+		t.Errorf("the collector ran %d times, want 1", runs)
+	}
+}
+
+func TestRegistryCollectMaxAge(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxAge   time.Duration
+		wantRuns int64
+	}{
+		{"no max age", 0, 1},
+		{"older than the max age", time.Nanosecond, 2},
+		{"within the max age", time.Hour, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &countingCollector{}
+			r := NewRegistry(c)
+			r.SetTTL(c.Name(), time.Hour)
+
+			ctx := withMaxAge(context.Background())
+			setMaxAge(ctx, tt.maxAge)
+			for i := 0; i < 2; i++ {
+				if _, err := r.Collect(ctx, c.Name()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if runs := c.runs.Load(); runs != tt.wantRuns {
+				t.Errorf("the collector ran %d times, want %d", runs, tt.wantRuns)
+			}
+		})
+	}
+}
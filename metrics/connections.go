@@ -0,0 +1,321 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Socket tables in /proc/net and the protocol they hold
+var inetTables = []struct {
+	file     string
+	protocol string
+}{
+	{"tcp", "tcp"},
+	{"tcp6", "tcp6"},
+	{"udp", "udp"},
+	{"udp6", "udp6"},
+}
+
+// States of TCP sockets as numbered by the kernel
+var tcpStates = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+}
+
+// States of UDP sockets, which only tell whether a peer was connected
+var udpStates = map[string]string{
+	"01": "ESTABLISHED",
+	"07": "UNCONN",
+}
+
+// States of Unix sockets, from socket_state in linux/net.h
+var unixStates = map[string]string{
+	"00": "FREE",
+	"01": "UNCONN",
+	"02": "CONNECTING",
+	"03": "ESTABLISHED",
+	"04": "DISCONNECTING",
+}
+
+// unixAcceptCon is the __SO_ACCEPTCON flag set on listening Unix sockets
+const unixAcceptCon = 0x10000
+
+// ConnectionFilter narrows down the sockets returned by selectConnections,
+// zero values match everything.
+type ConnectionFilter struct {
+	Protocol  string // tcp, udp or unix, optionally suffixed with 4 or 6
+	State     string
+	LocalPort int
+	PID       int
+}
+
+func (f ConnectionFilter) match(conn Connection) bool {
+	if f.Protocol != "" && !protocolMatches(f.Protocol, conn.Protocol) {
+		return false
+	}
+	if f.State != "" && !strings.EqualFold(conn.State, f.State) {
+		return false
+	}
+	if f.LocalPort != 0 && conn.LocalPort != f.LocalPort {
+		return false
+	}
+	if f.PID != 0 && conn.PID != f.PID {
+		return false
+	}
+	return true
+}
+
+// protocolMatches tells whether a socket of protocol, named after its table
+// in /proc/net, is selected by filter. tcp selects both tcp and tcp6 sockets
+// while tcp4 only selects the former.
+func protocolMatches(filter, protocol string) bool {
+	switch {
+	case strings.HasSuffix(filter, "4"):
+		return protocol == strings.TrimSuffix(filter, "4")
+	case strings.HasSuffix(filter, "6"):
+		return protocol == filter
+	default:
+		return strings.TrimSuffix(protocol, "6") == filter
+	}
+}
+
+// collectConnections reads the socket tables of the kernel and returns every
+// socket, with the process owning each one when it can be found.
+func collectConnections() ([]Connection, error) {
+	owners := socketOwners()
+
+	var connections []Connection
+	for _, table := range inetTables {
+		conns, err := readInetTable(HostFS().proc("net", table.file), table.protocol)
+		if err != nil {
+			// IPv6 may be disabled
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		connections = append(connections, conns...)
+	}
+
+	unixConns, err := readUnixTable(HostFS().proc("net", "unix"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	connections = append(connections, unixConns...)
+
+	for i := range connections {
+		connections[i].PID = owners[connections[i].Inode]
+	}
+	return connections, nil
+}
+
+// selectConnections keeps the sockets matching filter.
+func selectConnections(all []Connection, filter ConnectionFilter) []Connection {
+	matching := []Connection{}
+	for _, conn := range all {
+		if filter.match(conn) {
+			matching = append(matching, conn)
+		}
+	}
+	return matching
+}
+
+// selectListeners keeps the TCP sockets listening for connections and the
+// bound but unconnected UDP sockets, sorted by port.
+func selectListeners(all []Connection) []Connection {
+	listeners := []Connection{}
+	for _, conn := range all {
+		switch {
+		case strings.HasPrefix(conn.Protocol, "tcp") && conn.State == "LISTEN",
+			strings.HasPrefix(conn.Protocol, "udp") && conn.State == "UNCONN":
+			listeners = append(listeners, conn)
+		}
+	}
+
+	sort.SliceStable(listeners, func(i, j int) bool {
+		if listeners[i].LocalPort != listeners[j].LocalPort {
+			return listeners[i].LocalPort < listeners[j].LocalPort
+		}
+		return listeners[i].Protocol < listeners[j].Protocol
+	})
+
+	return listeners
+}
+
+// readInetTable parses one of the /proc/net/{tcp,tcp6,udp,udp6} tables.
+func readInetTable(path, protocol string) ([]Connection, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	states := tcpStates
+	if strings.HasPrefix(protocol, "udp") {
+		states = udpStates
+	}
+
+	var connections []Connection
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // Header
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+
+		localAddr, localPort, err := parseInetAddress(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		remoteAddr, remotePort, err := parseInetAddress(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		state, ok := states[fields[3]]
+		if !ok {
+			state = fields[3]
+		}
+
+		uid, _ := strconv.Atoi(fields[7])
+		inode, _ := strconv.ParseUint(fields[9], 10, 64)
+
+		connections = append(connections, Connection{
+			Protocol:      protocol,
+			LocalAddress:  localAddr,
+			LocalPort:     localPort,
+			RemoteAddress: remoteAddr,
+			RemotePort:    remotePort,
+			State:         state,
+			UID:           uid,
+			Inode:         inode,
+		})
+	}
+
+	return connections, scanner.Err()
+}
+
+// parseInetAddress decodes an address:port pair of a socket table, where
+// the address is written as native endian 32 bit words in hex.
+func parseInetAddress(s string) (string, int, error) {
+	addr, port, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", 0, fmt.Errorf("malformed address %q", s)
+	}
+
+	raw, err := hex.DecodeString(addr)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return "", 0, fmt.Errorf("malformed address %q", s)
+	}
+
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.LittleEndian.Uint32(raw[i:]))
+	}
+
+	portNum, err := strconv.ParseUint(port, 16, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("malformed port %q", s)
+	}
+
+	return ip.String(), int(portNum), nil
+}
+
+// readUnixTable parses /proc/net/unix.
+func readUnixTable(path string) ([]Connection, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var connections []Connection
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // Header
+	for scanner.Scan() {
+		// Num RefCount Protocol Flags Type St Inode Path
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 7 {
+			continue
+		}
+
+		flags, _ := strconv.ParseUint(fields[3], 16, 32)
+		state, ok := unixStates[fields[5]]
+		if !ok {
+			state = fields[5]
+		}
+		if flags&unixAcceptCon != 0 {
+			state = "LISTEN"
+		}
+
+		inode, _ := strconv.ParseUint(fields[6], 10, 64)
+
+		conn := Connection{
+			Protocol: "unix",
+			State:    state,
+			Inode:    inode,
+			UID:      -1,
+		}
+		if len(fields) > 7 {
+			conn.LocalAddress = fields[7]
+		}
+		connections = append(connections, conn)
+	}
+
+	return connections, scanner.Err()
+}
+
+// socketOwners maps socket inodes to the process holding them open. Only
+// the processes whose file descriptors are readable are found.
+func socketOwners() map[uint64]int {
+	owners := make(map[uint64]int)
+
+	fdDirs, _ := filepath.Glob(HostFS().proc("[0-9]*", "fd"))
+	for _, fdDir := range fdDirs {
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(fdDir)))
+		if err != nil {
+			continue
+		}
+
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(target, "socket:[") {
+				continue
+			}
+
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			if _, ok := owners[inode]; !ok {
+				owners[inode] = pid
+			}
+		}
+	}
+
+	return owners
+}
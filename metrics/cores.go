@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-sysinfo/types"
+	"github.com/shirou/gopsutil/cpu"
+)
+
+// collectCores gathers the timers of every logical CPU along with its
+// frequency and topology, sorted by CPU number.
+func collectCores() ([]Core, error) {
+	times, err := cpu.Times(true)
+	if err != nil {
+		return nil, err
+	}
+	// gopsutil returns no timers rather than an error when stat can not be
+	// read on Linux
+	if len(times) == 0 {
+		return nil, fmt.Errorf("no logical CPU found in %s", HostFS().proc("stat"))
+	}
+
+	cores := make([]Core, 0, len(times))
+	for _, stat := range times {
+		id, err := strconv.Atoi(strings.TrimPrefix(stat.CPU, "cpu"))
+		if err != nil {
+			continue
+		}
+
+		dir := HostFS().sys("devices", "system", "cpu", "cpu"+strconv.Itoa(id))
+		core := Core{
+			ID:           id,
+			Time:         cpuTimes(stat),
+			Frequency:    readFrequency(dir, "scaling_cur_freq"),
+			MinFrequency: readFrequency(dir, "cpuinfo_min_freq"),
+			MaxFrequency: readFrequency(dir, "cpuinfo_max_freq"),
+			Socket:       readSysInt(filepath.Join(dir, "topology", "physical_package_id")),
+			CoreID:       readSysInt(filepath.Join(dir, "topology", "core_id")),
+		}
+
+		// The NUMA node is only known from the nodeN link in the CPU directory
+		if nodes, _ := filepath.Glob(filepath.Join(dir, "node[0-9]*")); len(nodes) > 0 {
+			if node, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(nodes[0]), "node")); err == nil {
+				core.Node = &node
+			}
+		}
+
+		cores = append(cores, core)
+	}
+
+	sort.Slice(cores, func(i, j int) bool {
+		return cores[i].ID < cores[j].ID
+	})
+
+	return cores, nil
+}
+
+// cpuTimes converts the timers of gopsutil, in seconds, to the ones of
+// go-sysinfo used for the whole CPU.
+func cpuTimes(stat cpu.TimesStat) types.CPUTimes {
+	seconds := func(s float64) time.Duration {
+		return time.Duration(s * float64(time.Second))
+	}
+
+	return types.CPUTimes{
+		User:    seconds(stat.User),
+		System:  seconds(stat.System),
+		Idle:    seconds(stat.Idle),
+		IOWait:  seconds(stat.Iowait),
+		IRQ:     seconds(stat.Irq),
+		Nice:    seconds(stat.Nice),
+		SoftIRQ: seconds(stat.Softirq),
+		Steal:   seconds(stat.Steal),
+	}
+}
+
+// readFrequency reads a cpufreq attribute, in kHz, and returns it in MHz.
+// Frequencies are unknown when the kernel has no cpufreq driver, as in most
+// virtual machines.
+func readFrequency(cpuDir, attribute string) *float64 {
+	khz := readSysInt(filepath.Join(cpuDir, "cpufreq", attribute))
+	if khz == nil {
+		return nil
+	}
+	mhz := float64(*khz) / 1000
+	return &mhz
+}
+
+// readSysInt reads a sysfs attribute holding a single integer.
+func readSysInt(path string) *int {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+// coreUtilization computes the utilization of a logical CPU over window from
+// the samples kept by History.
+func coreUtilization(id int, window time.Duration) (CPUUtilization, error) {
+	prev, last, ok := History.Pair(window)
+	if !ok {
+		return CPUUtilization{}, errNotEnoughSamples
+	}
+
+	before, found := prev.CPU.core(id)
+	if !found {
+		return CPUUtilization{}, fmt.Errorf("cpu%d was not sampled", id)
+	}
+	after, found := last.CPU.core(id)
+	if !found {
+		return CPUUtilization{}, fmt.Errorf("cpu%d was not sampled", id)
+	}
+
+	if rebooted(prev, last) {
+		before = Core{}
+	}
+	return utilizationBetween(before.Time, after.Time), nil
+}
+
+// core returns the logical CPU numbered id.
+func (c CPU) core(id int) (Core, bool) {
+	for _, core := range c.Cores {
+		if core.ID == id {
+			return core, true
+		}
+	}
+	return Core{}, false
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"runtime/debug"
+
+	"github.com/elastic/go-sysinfo/types"
+	"github.com/graphql-go/graphql"
+)
+
+// Codes given to clients in the extensions of GraphQL errors, along with
+// the more specific ones of unavailable metrics (e.g. PSI_UNAVAILABLE).
+const (
+	codeNotFound            = "NOT_FOUND"
+	codePermissionDenied    = "PERMISSION_DENIED"
+	codeUnsupportedPlatform = "UNSUPPORTED_PLATFORM"
+	codeInternal            = "INTERNAL"
+)
+
+// metricError is an error given to clients with a code, and the collector
+// it comes from, in the extensions of the GraphQL error.
+type metricError struct {
+	code      string
+	collector string
+	err       error
+}
+
+func (e *metricError) Error() string {
+	return e.err.Error()
+}
+
+func (e *metricError) Unwrap() error {
+	return e.err
+}
+
+func (e *metricError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{
+		"code":   e.code,
+		"reason": e.err.Error(),
+	}
+	if e.collector != "" {
+		extensions["collector"] = e.collector
+	}
+	return extensions
+}
+
+// unavailableError reports a metric the host does not provide.
+func unavailableError(code, reason string) *metricError {
+	return &metricError{code: code, err: errors.New(reason)}
+}
+
+func notFoundError(format string, a ...interface{}) *metricError {
+	return &metricError{code: codeNotFound, err: fmt.Errorf(format, a...)}
+}
+
+// collectorError gives err a code, guessed from its cause when it has none,
+// and the name of the collector it comes from.
+func collectorError(collector string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var metricErr *metricError
+	if errors.As(err, &metricErr) {
+		if metricErr.collector != "" {
+			return metricErr
+		}
+		return &metricError{code: metricErr.code, collector: collector, err: metricErr.err}
+	}
+
+	code := codeInternal
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		code = codeNotFound
+	case errors.Is(err, fs.ErrPermission):
+		code = codePermissionDenied
+	case errors.Is(err, types.ErrNotImplemented), errors.Is(err, errors.ErrUnsupported):
+		code = codeUnsupportedPlatform
+	}
+	return &metricError{code: code, collector: collector, err: err}
+}
+
+// recoverResolvers makes every resolver of schema turn a panic into an
+// INTERNAL error of its field, so the rest of the query is still answered.
+// graphql-go recovers panics too but hands their raw value to clients and
+// fails the whole request when they happen in a non-null field.
+func recoverResolvers(schema graphql.Schema) {
+	for name, t := range schema.TypeMap() {
+		object, ok := t.(*graphql.Object)
+		if !ok || len(name) > 1 && name[:2] == "__" {
+			continue
+		}
+
+		for _, field := range object.Fields() {
+			resolve := field.Resolve
+			if resolve == nil {
+				resolve = graphql.DefaultResolveFn
+			}
+			field.Resolve = recoverResolve(object.Name()+"."+field.Name, resolve)
+			if field.Subscribe != nil {
+				field.Subscribe = recoverResolve(object.Name()+"."+field.Name, field.Subscribe)
+			}
+		}
+	}
+}
+
+func recoverResolve(name string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (value interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("panic resolving %s: %v\n%s", name, r, debug.Stack())
+				value = nil
+				err = &metricError{code: codeInternal, err: errors.New("Internal error")}
+			}
+		}()
+		return resolve(p)
+	}
+}
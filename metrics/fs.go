@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/elastic/go-sysinfo"
+	"github.com/elastic/go-sysinfo/types"
+	"github.com/shirou/gopsutil/disk"
+)
+
+// FS tells the collectors where the filesystems of the host are mounted,
+// so they can read them from a container or from a fake tree.
+type FS struct {
+	Proc string
+	Sys  string
+	Etc  string
+	Run  string
+}
+
+var hostFS atomic.Pointer[FS]
+
+func init() {
+	SetHostFS(DefaultFS())
+}
+
+// HostFS returns the FS the collectors read from, changed with SetHostFS.
+func HostFS() FS {
+	return *hostFS.Load()
+}
+
+// DefaultFS returns the usual mount points, moved by the HOST_PROC,
+// HOST_SYS, HOST_ETC and HOST_RUN variables like for gopsutil.
+func DefaultFS() FS {
+	return FS{
+		Proc: getenv("HOST_PROC", "/proc"),
+		Sys:  getenv("HOST_SYS", "/sys"),
+		Etc:  getenv("HOST_ETC", "/etc"),
+		Run:  getenv("HOST_RUN", "/run"),
+	}
+}
+
+// SetHostFS makes every collector read from fs. gopsutil only reads its
+// roots from the environment, so the HOST_* variables are set too.
+func SetHostFS(fs FS) {
+	hostFS.Store(&fs)
+	os.Setenv("HOST_PROC", fs.Proc)
+	os.Setenv("HOST_SYS", fs.Sys)
+	os.Setenv("HOST_ETC", fs.Etc)
+	os.Setenv("HOST_RUN", fs.Run)
+}
+
+func (fs FS) proc(elem ...string) string {
+	return filepath.Join(append([]string{fs.Proc}, elem...)...)
+}
+
+func (fs FS) sys(elem ...string) string {
+	return filepath.Join(append([]string{fs.Sys}, elem...)...)
+}
+
+func (fs FS) etc(elem ...string) string {
+	return filepath.Join(append([]string{fs.Etc}, elem...)...)
+}
+
+// root returns where the root of the host is when procfs was moved, assumed
+// to be the parent of the proc mount point. It is empty when procfs is not
+// moved, and ok is false when its parent can not be the root of the host.
+func (fs FS) root() (root string, ok bool) {
+	if filepath.Clean(fs.Proc) == "/proc" {
+		return "", true
+	}
+	if filepath.Base(fs.Proc) != "proc" {
+		return "", false
+	}
+	return filepath.Dir(fs.Proc), true
+}
+
+// sysinfoOptions points go-sysinfo to fs. It only takes the root of the
+// whole filesystem.
+func (fs FS) sysinfoOptions() []sysinfo.ProviderOption {
+	if root, ok := fs.root(); ok && root != "" && root != "/" {
+		return []sysinfo.ProviderOption{sysinfo.WithHostFS(root)}
+	}
+	return nil
+}
+
+// usage reads the usage of the filesystem the host mounts at mountpoint.
+// statfs only takes paths of the machine the server runs on, so when procfs
+// is moved the mountpoint is looked for below the root of the host.
+func (fs FS) usage(mountpoint string) (*disk.UsageStat, error) {
+	root, ok := fs.root()
+	if !ok {
+		return nil, unavailableError("HOST_ROOT_UNAVAILABLE",
+			"The usage of filesystems can not be read, the root of the host must be the parent of the proc root")
+	}
+
+	if root == "" {
+		return disk.Usage(mountpoint)
+	}
+
+	path := filepath.Join(root, mountpoint)
+	if _, err := os.Stat(path); err != nil {
+		return nil, unavailableError("HOST_ROOT_UNAVAILABLE",
+			fmt.Sprintf("The usage of %s can not be read, %s is not mounted", mountpoint, path))
+	}
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return nil, err
+	}
+	usage.Path = mountpoint
+	return usage, nil
+}
+
+// getHost returns the host as seen through HostFS.
+func getHost() (types.Host, error) {
+	return sysinfo.Host(HostFS().sysinfoOptions()...)
+}
+
+// getProcess returns the process pid as seen through HostFS.
+func getProcess(pid int) (types.Process, error) {
+	return sysinfo.Process(pid, HostFS().sysinfoOptions()...)
+}
+
+// getProcesses lists the processes as seen through HostFS.
+func getProcesses() ([]types.Process, error) {
+	return sysinfo.Processes(HostFS().sysinfoOptions()...)
+}
+
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
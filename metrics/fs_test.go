@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFSUsage(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"proc", "data"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		proc       string
+		mountpoint string
+		wantErr    bool
+	}{
+		{"roots not moved", "/proc", root, false},
+		{"under the host root", filepath.Join(root, "proc"), "/data", false},
+		{"host root itself", filepath.Join(root, "proc"), "/", false},
+		{"not under the host root", filepath.Join(root, "proc"), "/missing", true},
+		{"no host root", filepath.Join(root, "data"), "/", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage, err := FS{Proc: tt.proc}.usage(tt.mountpoint)
+			if tt.wantErr {
+				var metricErr *metricError
+				if !errors.As(err, &metricErr) || metricErr.Extensions()["code"] != "HOST_ROOT_UNAVAILABLE" {
+					t.Fatalf("usage() = %+v, %v, want a HOST_ROOT_UNAVAILABLE error", usage, err)
+				}
+				return
+			}
+			if err != nil || usage.Total == 0 || usage.Path != tt.mountpoint {
+				t.Fatalf("usage() = %+v, %v, want the usage of %s", usage, err, tt.mountpoint)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden responses of testdata/golden")
+
+// goldenQueries select, for every Query field, what the fixture host in
+// testdata/host decides. Values read from the machine running the tests,
+// like the clock or the usage of filesystems, are left out.
+var goldenQueries = map[string]string{
+	"host": `{ host { uniqueID containerized } }`,
+	"os":   `{ os { type family platform name version major minor patch codename build } }`,
+	"cpu": `{ cpu {
+		times { user(unit: SECONDS) system(unit: SECONDS) idle(unit: SECONDS) iowait(unit: SECONDS) irq(unit: SECONDS) nice(unit: SECONDS) softirq(unit: SECONDS) steal(unit: SECONDS) total(unit: SECONDS) }
+		load { one five fifteen }
+		info { cpu vendorId family model modelName stepping physicalId coreId cores mhz cacheSize microcode flags }
+		cores { id times { user(unit: SECONDS) idle(unit: SECONDS) } }
+	} }`,
+	"memory": `{ memory {
+		total available used free buffers cached shmem slab dirty writeback
+		swap { total used free cached swapIn swapOut }
+		hugepages { total free reserved surplus pageSize hugetlb }
+		meminfo(key: "MemTotal") { name value }
+		vmstat(key: "pgfault") { name value }
+	} }`,
+	"network": `{ network {
+		snmp(protocol: TCP) { protocol name value }
+		netstat(protocol: IP) { protocol name value }
+		interfaces { name hardwareAddr mtu operState speed rxBytes txBytes rxPackets txPackets rxErrors txErrors rxDrops txDrops }
+		connections { protocol localAddress localPort remoteAddress remotePort state uid inode pid }
+		listeners { protocol localAddress localPort state inode }
+	} }`,
+	"process":   `{ process(pid: 1) { pid ppid name exe cmdline state user threads rss vms } }`,
+	"processes": `{ processes(sortBy: NAME) { pid ppid name cmdline state user threads rss } }`,
+	"disk": `{ disk {
+		partitions(includeVirtual: true) { device mountpoint fstype opts }
+		io { name readCount writeCount readBytes writeBytes mergedReadCount mergedWriteCount readTime(unit: MILLISECONDS) writeTime(unit: MILLISECONDS) ioTime(unit: MILLISECONDS) iopsInProgress }
+	} }`,
+	"pressure": `{ pressure {
+		cpu { some { avg10 avg60 avg300 total(unit: MILLISECONDS) } full { avg10 total(unit: MILLISECONDS) } }
+		memory { some { avg10 total(unit: MILLISECONDS) } full { avg10 total(unit: MILLISECONDS) } }
+		io { some { avg10 total(unit: MILLISECONDS) } full { avg10 total(unit: MILLISECONDS) } }
+	} }`,
+	"cgroups": `{ cgroups(recursive: true) {
+		path
+		cpu { usage(unit: MILLISECONDS) user(unit: MILLISECONDS) system(unit: MILLISECONDS) periods throttledPeriods quota(unit: MILLISECONDS) period(unit: MILLISECONDS) }
+		memory { current max high low oom oomKill maxEvents }
+		pids { current max }
+		io { device readBytes writeBytes reads writes }
+		children { path memory { current } children { path pids { current max } } }
+	} }`,
+}
+
+// useFixtureHost makes the collectors read the fixture host, through a
+// registry of their own so nothing cached from the real host is returned.
+func useFixtureHost(t *testing.T) {
+	t.Helper()
+
+	fs, collectors := HostFS(), Collectors
+	SetHostFS(FS{
+		Proc: "testdata/host/proc",
+		Sys:  "testdata/host/sys",
+		Etc:  "testdata/host/etc",
+		Run:  "testdata/host/run",
+	})
+	Collectors = newDefaultRegistry()
+	t.Cleanup(func() {
+		SetHostFS(fs)
+		Collectors = collectors
+	})
+}
+
+func TestGolden(t *testing.T) {
+	useFixtureHost(t)
+
+	for name, query := range goldenQueries {
+		t.Run(name, func(t *testing.T) {
+			result := graphql.Do(graphql.Params{
+				Schema:        MetricsSchema,
+				RequestString: query,
+				Context:       context.Background(),
+			})
+			got, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", "golden", name+".json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v, run the tests with -update to write it", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("%s returned\n%s\nwant\n%s", name, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-sysinfo/types"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/net"
+)
+
+const (
+	DefaultSampleInterval = 10 * time.Second
+	DefaultRetention      = time.Hour
+
+	// MaxHistoryPoints is the most samples a history field returns
+	MaxHistoryPoints = 1000
+)
+
+// Sample is a snapshot of every subsystem taken by the Sampler.
+type Sample struct {
+	Timestamp time.Time
+	BootTime  time.Time
+	CPU       CPU
+	Memory    Memory
+	Disk      Disk
+	Usage     map[string]disk.UsageStat // Usage of each partition by device
+	IO        map[string]disk.IOCountersStat
+	NIC       map[string]net.IOCountersStat
+	Network   Network
+}
+
+// disk returns the sampled disk info narrowed down to device, the same way
+// the disk query does.
+func (s Sample) disk(device string) Disk {
+	diskObj := Disk{Partitions: s.Disk.Partitions}
+	if device == "" {
+		return diskObj
+	}
+
+	for _, partition := range s.Disk.Partitions {
+		if partition.Device == device {
+			diskObj.Device = device
+			diskObj.Partitions = []disk.PartitionStat{partition}
+			diskObj.UsageStat = s.Usage[device]
+			break
+		}
+	}
+	return diskObj
+}
+
+// Sampler periodically collects every subsystem into a bounded ring buffer,
+// so recent history can be queried without an external time-series database.
+type Sampler struct {
+	interval time.Duration
+
+	mu      sync.RWMutex
+	samples []Sample
+	next    int
+	full    bool
+}
+
+// History is the sampler the GraphQL history fields read from.
+var History = NewSampler(DefaultSampleInterval, DefaultRetention)
+
+// NewSampler creates a sampler that collects every interval and keeps
+// enough samples to cover retention.
+func NewSampler(interval, retention time.Duration) *Sampler {
+	if interval <= 0 {
+		interval = DefaultSampleInterval
+	}
+
+	size := int(retention / interval)
+	if size < 1 {
+		size = 1
+	}
+
+	return &Sampler{
+		interval: interval,
+		samples:  make([]Sample, size),
+	}
+}
+
+// Interval returns the time between two samples.
+func (s *Sampler) Interval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.interval
+}
+
+// size returns the number of samples the sampler keeps.
+func (s *Sampler) size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.samples)
+}
+
+// Reconfigure changes the interval and the retention of the sampler, which
+// must not be running, and drops its samples.
+func (s *Sampler) Reconfigure(interval, retention time.Duration) {
+	fresh := NewSampler(interval, retention)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.interval = fresh.interval
+	s.samples = fresh.samples
+	s.next = 0
+	s.full = false
+}
+
+// Run samples until ctx is done.
+func (s *Sampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval())
+	defer ticker.Stop()
+
+	s.Collect(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Collect(ctx)
+		}
+	}
+}
+
+// Collect takes a sample of every subsystem and adds it to the buffer,
+// overwriting the oldest one once the buffer is full. Subsystems whose
+// collector is disabled or fails are left empty. Cached snapshots are not
+// used, so rates are computed over the sampling interval.
+func (s *Sampler) Collect(ctx context.Context) {
+	sample := Sample{
+		Timestamp: time.Now(),
+		Usage:     make(map[string]disk.UsageStat),
+	}
+
+	if cpu, err := Collectors.Refresh(ctx, "cpu"); err == nil {
+		sample.CPU, _ = cpu.(CPU)
+	}
+	if memory, err := Collectors.Refresh(ctx, "memory"); err == nil {
+		sample.Memory, _ = memory.(Memory)
+	}
+	if network, err := Collectors.Refresh(ctx, "network"); err == nil {
+		sample.Network, _ = network.(Network)
+	}
+
+	if io, err := Collectors.Refresh(ctx, "diskio"); err == nil {
+		counters, _ := io.([]disk.IOCountersStat)
+		sample.IO = make(map[string]disk.IOCountersStat)
+		for _, counter := range counters {
+			sample.IO[counter.Name] = counter
+		}
+	}
+	if interfaces, err := Collectors.Refresh(ctx, "interfaces"); err == nil {
+		nics, _ := interfaces.([]Interface)
+		sample.NIC = make(map[string]net.IOCountersStat)
+		for _, iface := range nics {
+			sample.NIC[iface.Name] = iface.Counters
+		}
+	}
+
+	if host, err := Collectors.Refresh(ctx, "host"); err == nil {
+		info, _ := host.(types.HostInfo)
+		sample.BootTime = info.BootTime
+	}
+
+	if diskObj, err := Collectors.Refresh(ctx, "disk"); err == nil {
+		sample.Disk, _ = diskObj.(Disk)
+	}
+	if table, err := Collectors.Refresh(ctx, "partitions"); err == nil {
+		if table, ok := table.(*partitionTable); ok {
+			partitions, _ := table.withUsage(ctx, table.selectPartitions("", "", false))
+			for _, partition := range partitions {
+				if partition.usageErr == nil {
+					sample.Usage[partition.Device] = partition.UsageStat
+				}
+			}
+		}
+	}
+
+	s.add(sample)
+}
+
+// add puts sample in the buffer in place of the oldest one.
+func (s *Sampler) add(sample Sample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples[s.next] = sample
+	s.next = (s.next + 1) % len(s.samples)
+	if s.next == 0 {
+		s.full = true
+	}
+}
+
+// Range returns the samples taken between from and to, oldest first, and
+// skips samples taken less than step after the previous one returned. When
+// limit is set and the range holds more samples, step is widened so that at
+// most limit of them still cover the whole range.
+func (s *Sampler) Range(from, to time.Time, step time.Duration, limit int) []Sample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var inRange []Sample
+	for _, sample := range s.ordered() {
+		if !sample.Timestamp.Before(from) && !sample.Timestamp.After(to) {
+			inRange = append(inRange, sample)
+		}
+	}
+	if limit > 0 && len(inRange) > limit {
+		span := inRange[len(inRange)-1].Timestamp.Sub(inRange[0].Timestamp)
+		step = max(step, span/time.Duration(limit))
+	}
+
+	var samples []Sample
+	var last time.Time
+	for _, sample := range inRange {
+		if len(samples) > 0 && sample.Timestamp.Sub(last) < step {
+			continue
+		}
+		samples = append(samples, sample)
+		last = sample.Timestamp
+	}
+
+	if limit > 0 && len(samples) > limit {
+		samples = samples[:limit]
+	}
+	return samples
+}
+
+// Pair returns the latest sample together with the newest one taken at least
+// window before it, or the oldest one kept when the history is shorter than
+// window. ok is false until two samples have been taken.
+func (s *Sampler) Pair(window time.Duration) (prev, last Sample, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ordered := s.ordered()
+	if len(ordered) < 2 {
+		return Sample{}, Sample{}, false
+	}
+
+	last = ordered[len(ordered)-1]
+	prev = ordered[0]
+	for i := len(ordered) - 2; i >= 0; i-- {
+		if last.Timestamp.Sub(ordered[i].Timestamp) >= window {
+			prev = ordered[i]
+			break
+		}
+	}
+	return prev, last, true
+}
+
+// ordered returns the samples oldest first. The caller must hold the lock.
+func (s *Sampler) ordered() []Sample {
+	var ordered []Sample
+	if s.full {
+		ordered = append(ordered, s.samples[s.next:]...)
+	}
+	return append(ordered, s.samples[:s.next]...)
+}
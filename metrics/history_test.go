@@ -0,0 +1,224 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/net"
+)
+
+var epoch = time.Unix(1700000000, 0)
+
+// at returns a sample taken seconds after epoch.
+func at(seconds int) Sample {
+	return Sample{Timestamp: epoch.Add(time.Duration(seconds) * time.Second)}
+}
+
+// timestamps returns the seconds after epoch the samples were taken at.
+func timestamps(samples []Sample) []int {
+	seconds := []int{}
+	for _, sample := range samples {
+		seconds = append(seconds, int(sample.Timestamp.Sub(epoch)/time.Second))
+	}
+	return seconds
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNewSampler(t *testing.T) {
+	tests := []struct {
+		name                string
+		interval, retention time.Duration
+		wantInterval        time.Duration
+		wantSize            int
+	}{
+		{"default", DefaultSampleInterval, DefaultRetention, DefaultSampleInterval, 360},
+		{"retention shorter than interval", time.Minute, time.Second, time.Minute, 1},
+		{"no interval", 0, time.Minute, DefaultSampleInterval, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSampler(tt.interval, tt.retention)
+			if s.Interval() != tt.wantInterval || len(s.samples) != tt.wantSize {
+				t.Errorf("NewSampler() keeps %d samples every %v, want %d every %v",
+					len(s.samples), s.Interval(), tt.wantSize, tt.wantInterval)
+			}
+		})
+	}
+}
+
+func TestSamplerRing(t *testing.T) {
+	tests := []struct {
+		name  string
+		added int
+		want  []int
+	}{
+		{"empty", 0, []int{}},
+		{"partly filled", 2, []int{0, 1}},
+		{"full", 3, []int{0, 1, 2}},
+		{"wrapped", 4, []int{1, 2, 3}},
+		{"wrapped twice", 7, []int{4, 5, 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSampler(time.Second, 3*time.Second)
+			for i := 0; i < tt.added; i++ {
+				s.add(at(i))
+			}
+
+			got := timestamps(s.Range(epoch, epoch.Add(time.Hour), 0, 0))
+			if !equalInts(got, tt.want) {
+				t.Errorf("Range() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSamplerRange(t *testing.T) {
+	s := NewSampler(time.Second, 10*time.Second)
+	for i := 0; i < 10; i++ {
+		s.add(at(i))
+	}
+
+	tests := []struct {
+		name     string
+		from, to int
+		step     time.Duration
+		limit    int
+		want     []int
+	}{
+		{"everything", 0, 9, 0, 0, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"bounds included", 3, 5, 0, 0, []int{3, 4, 5}},
+		{"nothing in range", 20, 30, 0, 0, []int{}},
+		{"step", 0, 9, 3 * time.Second, 0, []int{0, 3, 6, 9}},
+		{"step shorter than interval", 0, 3, time.Millisecond, 0, []int{0, 1, 2, 3}},
+		{"under the limit", 0, 9, 0, 10, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"limit widens the step", 0, 9, 0, 5, []int{0, 2, 4, 6, 8}},
+		{"limit over a step", 0, 9, time.Second, 4, []int{0, 3, 6, 9}},
+		{"step over the limit", 0, 9, 5 * time.Second, 4, []int{0, 5}},
+		{"single sample", 0, 9, 0, 1, []int{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := at(tt.from).Timestamp, at(tt.to).Timestamp
+			if got := timestamps(s.Range(from, to, tt.step, tt.limit)); !equalInts(got, tt.want) {
+				t.Errorf("Range() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSamplerPair(t *testing.T) {
+	tests := []struct {
+		name     string
+		added    int
+		window   time.Duration
+		wantOK   bool
+		wantPrev int
+		wantLast int
+	}{
+		{"no sample", 0, time.Second, false, 0, 0},
+		{"one sample", 1, time.Second, false, 0, 0},
+		{"latest two", 5, 0, true, 3, 4},
+		{"window", 5, 2 * time.Second, true, 2, 4},
+		{"window between samples", 5, 1500 * time.Millisecond, true, 2, 4},
+		{"history shorter than window", 5, time.Hour, true, 0, 4},
+		{"after wrapping", 8, time.Hour, true, 3, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSampler(time.Second, 5*time.Second)
+			for i := 0; i < tt.added; i++ {
+				s.add(at(i))
+			}
+
+			prev, last, ok := s.Pair(tt.window)
+			if ok != tt.wantOK {
+				t.Fatalf("Pair() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			got := timestamps([]Sample{prev, last})
+			if want := []int{tt.wantPrev, tt.wantLast}; !equalInts(got, want) {
+				t.Errorf("Pair() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSamplerReconfigure(t *testing.T) {
+	s := NewSampler(time.Second, 3*time.Second)
+	for i := 0; i < 4; i++ {
+		s.add(at(i))
+	}
+
+	s.Reconfigure(2*time.Second, 10*time.Second)
+	if s.Interval() != 2*time.Second || len(s.samples) != 5 {
+		t.Fatalf("Reconfigure() keeps %d samples every %v", len(s.samples), s.Interval())
+	}
+	if _, _, ok := s.Pair(0); ok {
+		t.Fatal("Reconfigure() kept the samples")
+	}
+
+	for i := 0; i < 6; i++ {
+		s.add(at(i))
+	}
+	if got, want := timestamps(s.Range(epoch, epoch.Add(time.Hour), 0, 0)), []int{1, 2, 3, 4, 5}; !equalInts(got, want) {
+		t.Errorf("Range() = %v, want %v", got, want)
+	}
+}
+
+func TestInterfaceRate(t *testing.T) {
+	nic := func(seconds int, boot time.Time, recv, sent uint64) Sample {
+		sample := at(seconds)
+		sample.BootTime = boot
+		sample.NIC = map[string]net.IOCountersStat{"eth0": {BytesRecv: recv, BytesSent: sent}}
+		return sample
+	}
+	reboot := epoch.Add(time.Minute)
+
+	tests := []struct {
+		name    string
+		samples []Sample
+		want    InterfaceRate
+		wantErr error
+	}{
+		{"not enough samples", []Sample{nic(0, epoch, 0, 0)}, InterfaceRate{}, errNotEnoughSamples},
+		{"rate", []Sample{nic(0, epoch, 1000, 500), nic(10, epoch, 3000, 1500)}, InterfaceRate{RxBytes: 200, TxBytes: 100}, nil},
+		{"32 bit wrap", []Sample{nic(0, epoch, 1<<32-100, 0), nic(10, epoch, 900, 0)}, InterfaceRate{RxBytes: 100}, nil},
+		{"reboot", []Sample{nic(0, epoch, 5000, 5000), nic(10, reboot, 2000, 1000)}, InterfaceRate{RxBytes: 200, TxBytes: 100}, nil},
+		{"same timestamp", []Sample{nic(0, epoch, 0, 0), nic(0, epoch, 1000, 0)}, InterfaceRate{}, nil},
+	}
+
+	previous := History
+	t.Cleanup(func() { History = previous })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			History = NewSampler(time.Second, time.Minute)
+			for _, sample := range tt.samples {
+				History.add(sample)
+			}
+
+			got, err := interfaceRate("eth0", 0)
+			if err != tt.wantErr || got != tt.want {
+				t.Errorf("interfaceRate() = %+v, %v, want %+v, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const (
+	contentTypeJSON            = "application/json"
+	contentTypeGraphQL         = "application/graphql"
+	contentTypeGraphQLResponse = "application/graphql-response+json"
+
+	maxRequestBody = 1 << 20
+)
+
+// graphQLRequest is a GraphQL request as described by the GraphQL over HTTP
+// specification.
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// requestError is a failure to read a request, answered with status.
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// ServeGraphQL serves GraphQL requests over HTTP, accepting GET requests
+// with the query in the URL and POST requests with either a JSON or a
+// plain GraphQL body. WebSocket upgrades are handed to ServeWebSocket.
+func ServeGraphQL(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		ServeWebSocket(w, r)
+		return
+	}
+
+	responseType, ok := negotiateResponseType(r.Header.Get("Accept"))
+	if !ok {
+		writeRequestError(w, contentTypeJSON, &requestError{
+			status:  http.StatusNotAcceptable,
+			message: "Accept must allow " + contentTypeGraphQLResponse + " or " + contentTypeJSON,
+		})
+		return
+	}
+
+	req, err := readGraphQLRequest(r)
+	if err != nil {
+		var reqErr *requestError
+		if !errors.As(err, &reqErr) {
+			reqErr = &requestError{status: http.StatusBadRequest, message: err.Error()}
+		}
+		if reqErr.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", "GET, POST")
+		}
+		writeRequestError(w, responseType, reqErr)
+		return
+	}
+
+	switch operationType(req.Query, req.OperationName) {
+	case ast.OperationTypeSubscription:
+		writeRequestError(w, responseType, &requestError{
+			status:  http.StatusBadRequest,
+			message: "Subscriptions are only supported over WebSocket",
+		})
+		return
+	case ast.OperationTypeMutation:
+		if r.Method == http.MethodGet {
+			w.Header().Set("Allow", "POST")
+			writeRequestError(w, responseType, &requestError{
+				status:  http.StatusMethodNotAllowed,
+				message: "Mutations must be sent with POST",
+			})
+			return
+		}
+	}
+
+	cost, err := limitQuery(req.Query, req.OperationName)
+	if err != nil {
+		w.Header().Set("Content-Type", responseType+"; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"errors":     []gqlerrors.FormattedError{formatError(err)},
+			"extensions": map[string]interface{}{"cost": cost},
+		})
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         MetricsSchema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        WithRequestCache(r.Context()),
+	})
+	if cost > 0 {
+		result.Extensions = map[string]interface{}{"cost": cost}
+	}
+
+	status := http.StatusOK
+	// With the GraphQL response media type a request that could not be
+	// executed at all, as a parse or validation error, is a bad request
+	if responseType == contentTypeGraphQLResponse && result.Data == nil && result.HasErrors() {
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", responseType+"; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// readGraphQLRequest extracts the GraphQL request from the URL of GET
+// requests or from the body of POST requests.
+func readGraphQLRequest(r *http.Request) (graphQLRequest, error) {
+	var req graphQLRequest
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return req, &requestError{http.StatusBadRequest, "Variables must be a JSON object"}
+			}
+		}
+
+	case http.MethodPost:
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			return req, &requestError{http.StatusUnsupportedMediaType, "Missing or invalid Content-Type"}
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody))
+		if err != nil {
+			return req, err
+		}
+
+		switch mediaType {
+		case contentTypeJSON:
+			if err := json.Unmarshal(body, &req); err != nil {
+				return req, &requestError{http.StatusBadRequest, "Body must be a JSON encoded GraphQL request"}
+			}
+		case contentTypeGraphQL:
+			req.Query = string(body)
+		default:
+			return req, &requestError{http.StatusUnsupportedMediaType, "Content-Type must be " + contentTypeJSON + " or " + contentTypeGraphQL}
+		}
+
+	default:
+		return req, &requestError{http.StatusMethodNotAllowed, "Only GET and POST are supported"}
+	}
+
+	if req.Query == "" {
+		return req, &requestError{http.StatusBadRequest, "Missing query"}
+	}
+
+	return req, nil
+}
+
+// negotiateResponseType picks the response media type allowed by the Accept
+// header, preferring the GraphQL response type. Clients that do not send an
+// Accept header get plain JSON.
+func negotiateResponseType(accept string) (string, bool) {
+	if accept == "" {
+		return contentTypeJSON, true
+	}
+
+	allowsJSON := false
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case contentTypeGraphQLResponse:
+			return contentTypeGraphQLResponse, true
+		case contentTypeJSON, "application/*", "*/*":
+			allowsJSON = true
+		}
+	}
+
+	return contentTypeJSON, allowsJSON
+}
+
+// formatError keeps the extensions of errors returned outside of resolvers.
+func formatError(err error) gqlerrors.FormattedError {
+	formatted := gqlerrors.NewFormattedError(err.Error())
+	if extended, ok := err.(gqlerrors.ExtendedError); ok {
+		formatted.Extensions = extended.Extensions()
+	}
+	return formatted
+}
+
+func writeRequestError(w http.ResponseWriter, contentType string, err *requestError) {
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.WriteHeader(err.status)
+	// Requests that never reached execution have no data entry
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []gqlerrors.FormattedError{gqlerrors.NewFormattedError(err.message)},
+	})
+}
@@ -0,0 +1,268 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+const codeQueryTooComplex = "QUERY_TOO_COMPLEX"
+
+// Limits bounds the operations clients may run, zero lifting a limit.
+type Limits struct {
+	MaxDepth   int // Nesting of fields
+	MaxAliases int // Fields renamed with an alias
+	MaxCost    int // Sum of the cost of the fields selected
+}
+
+// DefaultLimits lets through the queries of the schema documentation, not
+// hundreds of copies of them.
+var DefaultLimits = Limits{MaxDepth: 10, MaxAliases: 20, MaxCost: 1000}
+
+var queryLimits atomic.Pointer[Limits]
+
+func init() {
+	SetLimits(DefaultLimits)
+}
+
+// SetLimits replaces the limits operations are checked against.
+func SetLimits(limits Limits) {
+	queryLimits.Store(&limits)
+}
+
+// fieldCosts is the cost of the fields that collect metrics or list many
+// items when resolved, named Type.field. Other fields cost 1.
+var fieldCosts = map[string]int{
+	"Query.host":           10,
+	"Query.os":             10,
+	"Query.cpu":            10,
+	"Query.memory":         10,
+	"Query.network":        10,
+	"Query.disk":           10,
+	"Query.pressure":       5,
+	"Query.process":        20,
+	"Query.processes":      50,
+	"Query.cgroups":        20,
+	"Subscription.cpu":     10,
+	"Subscription.memory":  10,
+	"Subscription.network": 10,
+	"Subscription.disk":    10,
+	"CPU.info":             10,
+	"Network.connections":  30,
+	"Network.listeners":    30,
+	"Network.interfaces":   10,
+	"Connection.process":   20,
+	"Disk.partitions":      10,
+	"Disk.io":              10,
+	"Cgroup.children":      10,
+}
+
+// listSizes estimates the number of items of the lists whose items are
+// costly to resolve or many, like samples and sockets. What is selected in
+// their items costs once per listBatch items. Other lists count once.
+var listSizes = map[string]func() int{
+	"CPU.history":         historySize,
+	"Memory.history":      historySize,
+	"Disk.history":        historySize,
+	"Network.history":     historySize,
+	"Network.connections": connectionCount(selectConnectionsAll),
+	"Network.listeners":   connectionCount(selectListeners),
+}
+
+const (
+	listBatch = 10
+
+	// defaultListSize is assumed for lists never collected yet
+	defaultListSize = 100
+)
+
+// historySize is the number of samples a history field can return.
+func historySize() int {
+	return min(History.size(), MaxHistoryPoints)
+}
+
+func selectConnectionsAll(all []Connection) []Connection {
+	return all
+}
+
+// connectionCount counts the sockets select keeps in the last snapshot of
+// the connections collector, without collecting them.
+func connectionCount(selectFn func([]Connection) []Connection) func() int {
+	return func() int {
+		snapshot, ok := Collectors.last("connections")
+		if !ok {
+			return defaultListSize
+		}
+		connections, ok := snapshot.([]Connection)
+		if !ok {
+			return defaultListSize
+		}
+		return len(selectFn(connections))
+	}
+}
+
+// queryCost is what a selection adds up to. Fields count once whatever the
+// number of items their lists resolve to, but the ones of listSizes.
+type queryCost struct {
+	depth   int
+	aliases int
+	cost    int
+}
+
+func (c *queryCost) add(other queryCost, depth int) {
+	c.depth = max(c.depth, depth+other.depth)
+	c.aliases = saturatingAdd(c.aliases, other.aliases)
+	c.cost = saturatingAdd(c.cost, other.cost)
+}
+
+// saturatingAdd keeps fragments spread over and over from overflowing.
+func saturatingAdd(a, b int) int {
+	if a > math.MaxInt32-b {
+		return math.MaxInt32
+	}
+	return a + b
+}
+
+func saturatingMul(a, b int) int {
+	if b > 0 && a > math.MaxInt32/b {
+		return math.MaxInt32
+	}
+	return a * b
+}
+
+// limitQuery returns the cost of the operation of query that will run, and
+// an error when it goes over the limits. Documents that do not parse cost
+// 0, graphql.Do reports them.
+func limitQuery(query, operationName string) (int, error) {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return 0, nil
+	}
+
+	a := &queryAnalyzer{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		costs:     make(map[string]queryCost),
+		visiting:  make(map[string]bool),
+	}
+	var operation *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			if operation == nil && (operationName == "" || def.Name != nil && def.Name.Value == operationName) {
+				operation = def
+			}
+		case *ast.FragmentDefinition:
+			a.fragments[def.Name.Value] = def
+		}
+	}
+	if operation == nil {
+		return 0, nil
+	}
+
+	var root graphql.Type = MetricsSchema.QueryType()
+	if operation.Operation == ast.OperationTypeSubscription {
+		root = MetricsSchema.SubscriptionType()
+	}
+	cost := a.selectionSet(root, operation.SelectionSet)
+
+	limits := queryLimits.Load()
+	switch {
+	case limits.MaxDepth > 0 && cost.depth > limits.MaxDepth:
+		err = fmt.Errorf("Query depth %d is over the limit of %d", cost.depth, limits.MaxDepth)
+	case limits.MaxAliases > 0 && cost.aliases > limits.MaxAliases:
+		err = fmt.Errorf("Query has %d aliases, over the limit of %d", cost.aliases, limits.MaxAliases)
+	case limits.MaxCost > 0 && cost.cost > limits.MaxCost:
+		err = fmt.Errorf("Query cost %d is over the limit of %d", cost.cost, limits.MaxCost)
+	}
+	if err != nil {
+		return cost.cost, &metricError{code: codeQueryTooComplex, err: err}
+	}
+	return cost.cost, nil
+}
+
+// queryAnalyzer measures a document, each fragment once.
+type queryAnalyzer struct {
+	fragments map[string]*ast.FragmentDefinition
+	costs     map[string]queryCost
+	visiting  map[string]bool
+}
+
+// selectionSet measures a selection on parent. Introspection fields are
+// left out, they do not read the host.
+func (a *queryAnalyzer) selectionSet(parent graphql.Type, set *ast.SelectionSet) queryCost {
+	var total queryCost
+	if set == nil {
+		return total
+	}
+
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			name := selection.Name.Value
+			if strings.HasPrefix(name, "__") {
+				continue
+			}
+
+			field := queryCost{depth: 1, cost: 1}
+			if selection.Alias != nil && selection.Alias.Value != name {
+				field.aliases = 1
+			}
+
+			var fieldType graphql.Type
+			batches := 1
+			if object, ok := parent.(*graphql.Object); ok {
+				if cost, ok := fieldCosts[object.Name()+"."+name]; ok {
+					field.cost = cost
+				}
+				if size, ok := listSizes[object.Name()+"."+name]; ok {
+					batches = max(1, (size()+listBatch-1)/listBatch)
+				}
+				if def, ok := object.Fields()[name]; ok {
+					fieldType, _ = graphql.GetNamed(def.Type).(graphql.Type)
+				}
+			}
+			items := a.selectionSet(fieldType, selection.SelectionSet)
+			items.cost = saturatingMul(items.cost, batches)
+			field.add(items, 1)
+			total.add(field, 0)
+
+		case *ast.InlineFragment:
+			total.add(a.selectionSet(a.typeCondition(selection.TypeCondition, parent), selection.SelectionSet), 0)
+
+		case *ast.FragmentSpread:
+			total.add(a.fragment(selection.Name.Value), 0)
+		}
+	}
+	return total
+}
+
+// fragment measures the fragment called name, and ignores unknown and
+// cyclic ones the validation of the document rejects.
+func (a *queryAnalyzer) fragment(name string) queryCost {
+	if cost, ok := a.costs[name]; ok {
+		return cost
+	}
+	def, ok := a.fragments[name]
+	if !ok || a.visiting[name] {
+		return queryCost{}
+	}
+
+	a.visiting[name] = true
+	cost := a.selectionSet(a.typeCondition(def.TypeCondition, nil), def.SelectionSet)
+	delete(a.visiting, name)
+
+	a.costs[name] = cost
+	return cost
+}
+
+func (a *queryAnalyzer) typeCondition(condition *ast.Named, parent graphql.Type) graphql.Type {
+	if condition == nil {
+		return parent
+	}
+	return MetricsSchema.Type(condition.Name.Value)
+}
@@ -0,0 +1,208 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// nestedCgroups selects the path of cgroups levels deep in the hierarchy,
+// levels + 2 fields deep.
+func nestedCgroups(levels int) string {
+	return "{ cgroups { " + strings.Repeat("children { ", levels) + "path" + strings.Repeat(" }", levels) + " } }"
+}
+
+// aliasedHosts selects the host count times, under a different alias each.
+func aliasedHosts(count int) string {
+	var query strings.Builder
+	query.WriteString("{")
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&query, " h%d: host { hostname }", i)
+	}
+	query.WriteString(" }")
+	return query.String()
+}
+
+// doublingFragments spreads each fragment twice in the next one, the last
+// of count fragments selecting the host 2^count times.
+func doublingFragments(count int) string {
+	var query strings.Builder
+	fmt.Fprintf(&query, "{ ...F%d } fragment F0 on Query { host { hostname } }", count)
+	for i := 1; i <= count; i++ {
+		fmt.Fprintf(&query, " fragment F%d on Query { ...F%d ...F%d }", i, i-1, i-1)
+	}
+	return query.String()
+}
+
+// useListSizes makes the history hold samples and the connections
+// collector hold sockets, half of them listening, when sockets is not 0.
+func useListSizes(t *testing.T, samples, sockets int) {
+	t.Helper()
+
+	history, collectors := History, Collectors
+	History = NewSampler(time.Second, time.Duration(samples)*time.Second)
+	Collectors = newDefaultRegistry()
+	t.Cleanup(func() {
+		History, Collectors = history, collectors
+	})
+
+	if sockets > 0 {
+		connections := make([]Connection, sockets)
+		for i := range connections {
+			connections[i] = Connection{Protocol: "tcp", State: "ESTABLISHED"}
+			if i%2 == 0 {
+				connections[i].State = "LISTEN"
+			}
+		}
+		Collectors.store(context.Background(), "connections", connections)
+	}
+}
+
+func TestLimitQuery(t *testing.T) {
+	SetLimits(DefaultLimits)
+	t.Cleanup(func() { SetLimits(DefaultLimits) })
+	useListSizes(t, 360, 0)
+
+	tests := []struct {
+		name      string
+		query     string
+		operation string
+		wantCost  int
+		errMsg    string // Empty when the query is let through
+	}{
+		{"costly fields", `{ cpu { info { modelName } } }`, "", 21, ""},
+		{"unknown field", `{ nothing }`, "", 1, ""},
+		{"syntax error", `{ cpu {`, "", 0, ""},
+		{"introspection", `{ __schema { types { name fields { name } } } }`, "", 0, ""},
+		{"typename", `{ __typename cpu { info { modelName } } }`, "", 21, ""},
+		{"every selection counts", `{ host { hostname } host { hostname } }`, "", 22, ""},
+		{"inline fragment", `{ ... on Query { host { hostname } } }`, "", 11, ""},
+		{"named operation", `query A { host { hostname } } query B { processes { pid } }`, "B", 51, ""},
+		{"unknown operation", `query A { host { hostname } }`, "B", 0, ""},
+		{"subscription", `subscription { cpu { info { modelName } } }`, "", 21, ""},
+
+		{"depth at limit", nestedCgroups(8), "", 20 + 8*10 + 1, ""},
+		{"depth over limit", nestedCgroups(9), "", 20 + 9*10 + 1, "Query depth 11 is over the limit of 10"},
+		{"depth through fragments", `{ cgroups { ...A } } fragment A on Cgroup { children { ...B } } fragment B on Cgroup { children { children { children { children { children { children { children { children { path } } } } } } } } }`, "", 20 + 9*10 + 1, "Query depth 11 is over the limit of 10"},
+
+		{"aliases at limit", aliasedHosts(20), "", 20 * 11, ""},
+		{"aliases over limit", aliasedHosts(21), "", 21 * 11, "Query has 21 aliases, over the limit of 20"},
+		{"alias of the same name", `{ host: host { hostname: hostname } }`, "", 11, ""},
+		{"aliases in fragments", `{ ...A ...A } fragment A on Query { a: host { b: hostname } }`, "", 22, ""},
+
+		{"cost over limit", `{ processes { pid } ` + strings.Repeat("network { connections { localPort } } ", 25) + `}`, "", 50 + 1 + 25*(10+30+defaultListSize/listBatch), "Query cost 1301 is over the limit of 1000"},
+		{"history", `{ memory { history { timestamp value { total } } } }`, "", 10 + 1 + 36*3, ""},
+		{"history in fragments", `{ memory { history { ...V } } } fragment V on MemorySample { value { total } }`, "", 10 + 1 + 36*2, ""},
+		{"fragment cycle", `{ ...A } fragment A on Query { host { hostname } ...B } fragment B on Query { ...A }`, "", 11, ""},
+		{"self spread", `{ ...A } fragment A on Query { host { hostname } ...A }`, "", 11, ""},
+		{"unknown fragment", `{ ...A }`, "", 0, ""},
+		{"doubling fragments", doublingFragments(6), "", 64 * 11, ""},
+		{"saturated cost", doublingFragments(40), "", math.MaxInt32, fmt.Sprintf("Query cost %d is over the limit of 1000", math.MaxInt32)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done := make(chan struct{})
+			var cost int
+			var err error
+			go func() {
+				defer close(done)
+				cost, err = limitQuery(tt.query, tt.operation)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("limitQuery() did not return")
+			}
+
+			if cost != tt.wantCost {
+				t.Errorf("limitQuery() cost = %d, want %d", cost, tt.wantCost)
+			}
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("limitQuery() = %v, want no error", err)
+				}
+				return
+			}
+
+			var metricErr *metricError
+			if !errors.As(err, &metricErr) || err.Error() != tt.errMsg {
+				t.Fatalf("limitQuery() = %v, want %q", err, tt.errMsg)
+			}
+			if code := metricErr.Extensions()["code"]; code != codeQueryTooComplex {
+				t.Errorf("limitQuery() error code = %v, want %s", code, codeQueryTooComplex)
+			}
+		})
+	}
+}
+
+func TestLimitQueryLimits(t *testing.T) {
+	t.Cleanup(func() { SetLimits(DefaultLimits) })
+
+	query := nestedCgroups(9)
+	tests := []struct {
+		name    string
+		limits  Limits
+		wantErr bool
+	}{
+		{"lifted", Limits{}, false},
+		{"over depth", Limits{MaxDepth: 10}, true},
+		{"over cost", Limits{MaxCost: 110}, true},
+		{"at cost", Limits{MaxCost: 111}, false},
+		{"no alias", Limits{MaxAliases: 1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetLimits(tt.limits)
+			if _, err := limitQuery(query, ""); (err != nil) != tt.wantErr {
+				t.Errorf("limitQuery() = %v, want an error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLimitQueryListSizes(t *testing.T) {
+	SetLimits(DefaultLimits)
+	t.Cleanup(func() { SetLimits(DefaultLimits) })
+
+	cpuHistory := `{ cpu { history { timestamp value { load { one five fifteen } times { user system idle iowait irq nice softirq steal total } } } } }`
+	tests := []struct {
+		name     string
+		samples  int
+		sockets  int
+		query    string
+		wantCost int
+		errMsg   string // Empty when the query is let through
+	}{
+		{"short history", 60, 0, cpuHistory, 10 + 1 + 6*16, ""},
+		{"default history", 360, 0, cpuHistory, 10 + 1 + 36*16, ""},
+		{"long history", 3600, 0, cpuHistory, 10 + 1 + 100*16, "Query cost 1611 is over the limit of 1000"},
+		{"few sockets", 1, 8, `{ network { connections { localPort process { name } } } }`, 10 + 30 + 1*22, ""},
+		{"many sockets", 1, 400, `{ network { connections { localPort process { name } } } }`, 10 + 30 + 40*22, ""},
+		{"too many sockets", 1, 1000, `{ network { connections { localPort process { name } } } }`, 10 + 30 + 100*22, "Query cost 2240 is over the limit of 1000"},
+		{"listeners only", 1, 1000, `{ network { listeners { localPort process { name } } } }`, 10 + 30 + 50*22, "Query cost 1140 is over the limit of 1000"},
+		{"sockets never collected", 1, 0, `{ network { listeners { localPort process { name } } } }`, 10 + 30 + 10*22, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useListSizes(t, tt.samples, tt.sockets)
+
+			cost, err := limitQuery(tt.query, "")
+			if cost != tt.wantCost {
+				t.Errorf("limitQuery() cost = %d, want %d", cost, tt.wantCost)
+			}
+			switch {
+			case tt.errMsg == "" && err != nil:
+				t.Errorf("limitQuery() = %v, want no error", err)
+			case tt.errMsg != "" && (err == nil || err.Error() != tt.errMsg):
+				t.Errorf("limitQuery() = %v, want %q", err, tt.errMsg)
+			}
+		})
+	}
+}
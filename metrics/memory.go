@@ -0,0 +1,60 @@
+package metrics
+
+import "sort"
+
+// meminfo returns every value of /proc/meminfo, in bytes for the ones given
+// in kB. go-sysinfo moves a few of them out of its Metrics map.
+func (m Memory) meminfo() map[string]uint64 {
+	values := make(map[string]uint64, len(m.Metrics)+5)
+	for key, value := range m.Metrics {
+		values[key] = value
+	}
+	values["MemTotal"] = m.Total
+	values["MemFree"] = m.Free
+	values["MemAvailable"] = m.Available
+	values["SwapTotal"] = m.VirtualTotal
+	values["SwapFree"] = m.VirtualFree
+	return values
+}
+
+func (m Memory) swap() Swap {
+	return Swap{
+		Total:  m.VirtualTotal,
+		Used:   m.VirtualUsed,
+		Free:   m.VirtualFree,
+		Cached: m.Metrics["SwapCached"],
+		In:     m.VMStat["pswpin"],
+		Out:    m.VMStat["pswpout"],
+	}
+}
+
+func (m Memory) hugepages() Hugepages {
+	return Hugepages{
+		Total:    m.Metrics["HugePages_Total"],
+		Free:     m.Metrics["HugePages_Free"],
+		Reserved: m.Metrics["HugePages_Rsvd"],
+		Surplus:  m.Metrics["HugePages_Surp"],
+		PageSize: m.Metrics["Hugepagesize"],
+		Hugetlb:  m.Metrics["Hugetlb"],
+	}
+}
+
+// statList returns the values sorted by name, or only the one called name
+// when it is set.
+func statList(values map[string]uint64, name string) []Stat {
+	if name != "" {
+		if value, ok := values[name]; ok {
+			return []Stat{{Name: name, Value: value}}
+		}
+		return []Stat{}
+	}
+
+	stats := make([]Stat, 0, len(values))
+	for name, value := range values {
+		stats = append(stats, Stat{Name: name, Value: value})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var errPSIUnavailable = unavailableError("PSI_UNAVAILABLE",
+	"Pressure stall information is not available, the kernel must be built with CONFIG_PSI and booted without psi=0")
+
+// Pressure locates the pressure files of the host or of a cgroup, which are
+// only read when asked for.
+type Pressure struct {
+	dir    string
+	suffix string
+}
+
+// PressureResource is the pressure on a resource. Full is nil for the CPU
+// of the host on kernels older than 5.13.
+type PressureResource struct {
+	Some PressureStat
+	Full *PressureStat
+}
+
+// PressureStat is the share of time some or all tasks were stalled.
+type PressureStat struct {
+	Avg10  float64 // Percent
+	Avg60  float64 // Percent
+	Avg300 float64 // Percent
+	Total  time.Duration
+}
+
+// systemPressure returns the pressure of the whole host.
+func systemPressure() (Pressure, error) {
+	if _, err := os.Stat(HostFS().proc("pressure")); err != nil {
+		return Pressure{}, errPSIUnavailable
+	}
+	return Pressure{dir: HostFS().proc("pressure")}, nil
+}
+
+// cgroupPressure returns the pressure of a cgroup, given by its path in
+// the cgroup v2 hierarchy (e.g. /system.slice/ssh.service).
+func cgroupPressure(cgroup string) (Pressure, error) {
+	root, err := cgroup2Root()
+	if err != nil {
+		return Pressure{}, err
+	}
+
+	dir := filepath.Join(root, filepath.Clean("/"+cgroup))
+	if _, err := os.Stat(dir); err != nil {
+		return Pressure{}, notFoundError("cgroup %q not found", cgroup)
+	}
+	return Pressure{dir: dir, suffix: ".pressure"}, nil
+}
+
+// resource reads the pressure on resource, one of cpu, memory or io.
+func (p Pressure) resource(resource string) (PressureResource, error) {
+	file, err := os.Open(filepath.Join(p.dir, resource+p.suffix))
+	if err != nil {
+		if os.IsNotExist(err) || errors.Is(err, syscall.EOPNOTSUPP) {
+			return PressureResource{}, errPSIUnavailable
+		}
+		return PressureResource{}, err
+	}
+	defer file.Close()
+
+	var pressure PressureResource
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 5 {
+			continue
+		}
+
+		stat, err := parsePressureStat(fields[1:])
+		if err != nil {
+			return PressureResource{}, fmt.Errorf("%s: %w", file.Name(), err)
+		}
+
+		switch fields[0] {
+		case "some":
+			pressure.Some = stat
+		case "full":
+			pressure.Full = &stat
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		// Reading fails when PSI was disabled at boot
+		if errors.Is(err, syscall.EOPNOTSUPP) {
+			return PressureResource{}, errPSIUnavailable
+		}
+		return PressureResource{}, err
+	}
+	return pressure, nil
+}
+
+func parsePressureStat(fields []string) (PressureStat, error) {
+	var stat PressureStat
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return stat, fmt.Errorf("malformed field %q", field)
+		}
+
+		if key == "total" {
+			// Microseconds
+			total, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return stat, fmt.Errorf("malformed field %q", field)
+			}
+			stat.Total = time.Duration(total) * time.Microsecond
+			continue
+		}
+
+		avg, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return stat, fmt.Errorf("malformed field %q", field)
+		}
+		switch key {
+		case "avg10":
+			stat.Avg10 = avg
+		case "avg60":
+			stat.Avg60 = avg
+		case "avg300":
+			stat.Avg300 = avg
+		}
+	}
+	return stat, nil
+}
@@ -0,0 +1,185 @@
+package metrics
+
+import (
+	"bufio"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-sysinfo/types"
+	"github.com/shirou/gopsutil/process"
+)
+
+// newProcess gathers the details of a single process. The base information
+// comes from go-sysinfo, state and thread count are filled in by gopsutil as
+// go-sysinfo does not expose them.
+func newProcess(proc types.Process) (Process, error) {
+	ps, err := process.NewProcess(int32(proc.PID()))
+	if err != nil {
+		return Process{}, err
+	}
+
+	info, err := proc.Info()
+	if err != nil {
+		// go-sysinfo gives up when the executable link is not readable, as
+		// for processes of other users, so fall back to what gopsutil reads
+		// from the world readable stat files
+		info.PID = proc.PID()
+		if info.Name, err = ps.Name(); err != nil {
+			return Process{}, err
+		}
+		ppid, _ := ps.Ppid()
+		info.PPID = int(ppid)
+		info.Args, _ = ps.CmdlineSlice()
+		if createTime, err := ps.CreateTime(); err == nil {
+			info.StartTime = time.UnixMilli(createTime)
+		}
+	}
+
+	procObj := Process{
+		PID:       info.PID,
+		PPID:      info.PPID,
+		Name:      info.Name,
+		Exe:       info.Exe,
+		Cmdline:   info.Args,
+		StartTime: info.StartTime,
+	}
+
+	if mem, err := proc.Memory(); err == nil {
+		procObj.RSS = mem.Resident
+		procObj.VMS = mem.Virtual
+	}
+
+	// CPU usage averaged over the lifetime of the process
+	if times, err := proc.CPUTime(); err == nil {
+		if elapsed := time.Since(info.StartTime); elapsed > 0 {
+			procObj.CPUUsage = 100 * float64(times.User+times.System) / float64(elapsed)
+		}
+	}
+
+	if owner, err := proc.User(); err == nil {
+		procObj.User = lookupUser(owner.UID)
+	}
+
+	procObj.State, _ = ps.Status()
+	procObj.NumThreads, _ = ps.NumThreads()
+
+	return procObj, nil
+}
+
+// userNames holds the names of the users of the passwd file of the host,
+// read again when it changes.
+var userNames struct {
+	mu      sync.Mutex
+	path    string
+	modTime time.Time
+	names   map[string]string
+}
+
+// lookupUser returns the name of uid in the passwd file of HostFS, or uid
+// itself when unknown. Users of other sources, like LDAP, are only found
+// when the host is not read from another root.
+func lookupUser(uid string) string {
+	fs := HostFS()
+	if name, ok := passwdNames(fs.etc("passwd"))[uid]; ok {
+		return name
+	}
+	if fs.Etc == "/etc" {
+		if u, err := user.LookupId(uid); err == nil {
+			return u.Username
+		}
+	}
+	return uid
+}
+
+func passwdNames(path string) map[string]string {
+	userNames.mu.Lock()
+	defer userNames.mu.Unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	if path == userNames.path && info.ModTime().Equal(userNames.modTime) {
+		return userNames.names
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	names := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// name:password:uid:gid:gecos:home:shell
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 3 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if _, ok := names[fields[2]]; !ok {
+			names[fields[2]] = fields[0]
+		}
+	}
+
+	userNames.path, userNames.modTime, userNames.names = path, info.ModTime(), names
+	return names
+}
+
+// collectProcesses returns every process.
+func collectProcesses() ([]Process, error) {
+	procs, err := getProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	processes := make([]Process, 0, len(procs))
+	for _, proc := range procs {
+		// Processes may exit while being listed, skip those
+		procObj, err := newProcess(proc)
+		if err != nil {
+			continue
+		}
+		processes = append(processes, procObj)
+	}
+
+	return processes, nil
+}
+
+// selectProcesses returns the processes whose name or command line contains
+// filter, ordered by sortBy and truncated to limit entries when limit > 0.
+func selectProcesses(all []Process, filter string, sortBy string, limit int) []Process {
+	processes := make([]Process, 0, len(all))
+	for _, procObj := range all {
+		if filter != "" && !strings.Contains(procObj.Name, filter) &&
+			!strings.Contains(strings.Join(procObj.Cmdline, " "), filter) {
+			continue
+		}
+		processes = append(processes, procObj)
+	}
+
+	sort.SliceStable(processes, func(i, j int) bool {
+		switch sortBy {
+		case "Name":
+			return processes[i].Name < processes[j].Name
+		case "CPU":
+			return processes[i].CPUUsage > processes[j].CPUUsage
+		case "Memory":
+			return processes[i].RSS > processes[j].RSS
+		case "StartTime":
+			return processes[i].StartTime.Before(processes[j].StartTime)
+		default:
+			return processes[i].PID < processes[j].PID
+		}
+	})
+
+	if limit > 0 && limit < len(processes) {
+		processes = processes[:limit]
+	}
+
+	return processes
+}
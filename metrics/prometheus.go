@@ -0,0 +1,287 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	promNamespace = "gometric"
+
+	promTextContentType        = "text/plain; version=0.0.4; charset=utf-8"
+	promOpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// snmpGauges are the SNMP counters that report a current value or a
+// setting rather than a monotonically increasing count.
+var snmpGauges = map[string]bool{
+	"Forwarding":   true,
+	"DefaultTTL":   true,
+	"RtoAlgorithm": true,
+	"RtoMin":       true,
+	"RtoMax":       true,
+	"MaxConn":      true,
+	"CurrEstab":    true,
+}
+
+type promSample struct {
+	labels []string // Label name and value pairs
+	value  float64
+}
+
+type promFamily struct {
+	name    string
+	help    string
+	counter bool
+	samples []promSample
+}
+
+func (f *promFamily) add(value float64, labels ...string) {
+	f.samples = append(f.samples, promSample{labels: labels, value: value})
+}
+
+// ServePrometheus renders the host metrics in the Prometheus text exposition
+// format, or in OpenMetrics when the client asks for it.
+func ServePrometheus(w http.ResponseWriter, r *http.Request) {
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+
+	if openMetrics {
+		w.Header().Set("Content-Type", promOpenMetricsContentType)
+	} else {
+		w.Header().Set("Content-Type", promTextContentType)
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, family := range collectPrometheus(WithRequestCache(r.Context())) {
+		writePromFamily(bw, family, openMetrics)
+	}
+	if openMetrics {
+		bw.WriteString("# EOF\n")
+	}
+	bw.Flush()
+}
+
+// collectPrometheus turns the snapshots of the collectors into metric
+// families, leaving out the subsystems whose collector is disabled or fails.
+func collectPrometheus(ctx context.Context) []*promFamily {
+	var families []*promFamily
+
+	// CPU
+	if snapshot, err := Collectors.Collect(ctx, "cpu"); err == nil {
+		cpuObj := snapshot.(CPU)
+		times := cpuObj.Time
+		cpuSeconds := &promFamily{
+			name:    "cpu_seconds",
+			help:    "Seconds the CPUs spent in each mode.",
+			counter: true,
+		}
+		cpuSeconds.add(times.User.Seconds(), "mode", "user")
+		cpuSeconds.add(times.System.Seconds(), "mode", "system")
+		cpuSeconds.add(times.Idle.Seconds(), "mode", "idle")
+		cpuSeconds.add(times.IOWait.Seconds(), "mode", "iowait")
+		cpuSeconds.add(times.IRQ.Seconds(), "mode", "irq")
+		cpuSeconds.add(times.Nice.Seconds(), "mode", "nice")
+		cpuSeconds.add(times.SoftIRQ.Seconds(), "mode", "softirq")
+		cpuSeconds.add(times.Steal.Seconds(), "mode", "steal")
+		families = append(families, cpuSeconds)
+
+		if avg := cpuObj.Load; avg != nil {
+			families = append(families,
+				&promFamily{name: "load1", help: "1 minute load average.", samples: []promSample{{value: avg.One}}},
+				&promFamily{name: "load5", help: "5 minutes load average.", samples: []promSample{{value: avg.Five}}},
+				&promFamily{name: "load15", help: "15 minutes load average.", samples: []promSample{{value: avg.Fifteen}}},
+			)
+		}
+	}
+
+	// Memory
+	if snapshot, err := Collectors.Collect(ctx, "memory"); err == nil {
+		mem := snapshot.(Memory)
+		for _, m := range []struct {
+			name  string
+			help  string
+			value uint64
+		}{
+			{"memory_total_bytes", "Total physical memory in bytes.", mem.Total},
+			{"memory_used_bytes", "Total used memory in bytes.", mem.Used},
+			{"memory_available_bytes", "Amount of memory available without swapping in bytes.", mem.Available},
+			{"memory_free_bytes", "Amount of memory not used by the system in bytes.", mem.Free},
+			{"memory_virtual_total_bytes", "Total virtual memory in bytes.", mem.VirtualTotal},
+			{"memory_virtual_used_bytes", "Total used virtual memory in bytes.", mem.VirtualUsed},
+			{"memory_virtual_free_bytes", "Virtual memory that is not used in bytes.", mem.VirtualFree},
+		} {
+			families = append(families, &promFamily{
+				name:    m.name,
+				help:    m.help,
+				samples: []promSample{{value: float64(m.value)}},
+			})
+		}
+	}
+
+	// Disk
+	if snapshot, err := Collectors.Collect(ctx, "partitions"); err == nil {
+		diskTotal := &promFamily{name: "disk_total_bytes", help: "Total storage space in bytes."}
+		diskFree := &promFamily{name: "disk_free_bytes", help: "Free storage space in bytes."}
+		diskUsed := &promFamily{name: "disk_used_bytes", help: "Used storage space in bytes."}
+		inodesTotal := &promFamily{name: "disk_inodes", help: "Total inodes."}
+		inodesFree := &promFamily{name: "disk_inodes_free", help: "Free inodes."}
+		inodesUsed := &promFamily{name: "disk_inodes_used", help: "Used inodes."}
+
+		table := snapshot.(*partitionTable)
+		partitions, _ := table.withUsage(ctx, table.selectPartitions("", "", false))
+		for _, partition := range partitions {
+			if partition.usageErr != nil {
+				continue
+			}
+			usage := partition.UsageStat
+
+			labels := []string{
+				"device", partition.Device,
+				"mountpoint", partition.Mountpoint,
+				"fstype", partition.Fstype,
+			}
+			diskTotal.add(float64(usage.Total), labels...)
+			diskFree.add(float64(usage.Free), labels...)
+			diskUsed.add(float64(usage.Used), labels...)
+			inodesTotal.add(float64(usage.InodesTotal), labels...)
+			inodesFree.add(float64(usage.InodesFree), labels...)
+			inodesUsed.add(float64(usage.InodesUsed), labels...)
+		}
+
+		families = append(families, diskTotal, diskFree, diskUsed, inodesTotal, inodesFree, inodesUsed)
+	}
+
+	// Network
+	if snapshot, err := Collectors.Collect(ctx, "network"); err == nil {
+		counters := snapshot.(Network).Network
+		netstat := &promFamily{
+			name:    "network_netstat",
+			help:    "Extended network counters from /proc/net/netstat.",
+			counter: true,
+		}
+		addPromCounterMap(netstat, "IPExt", counters.Netstat.IPExt)
+		addPromCounterMap(netstat, "TCPExt", counters.Netstat.TCPExt)
+
+		snmp := &promFamily{
+			name:    "network_snmp",
+			help:    "SNMP network counters from /proc/net/snmp.",
+			counter: true,
+		}
+		snmpGauge := &promFamily{
+			name: "network_snmp_value",
+			help: "SNMP network values and settings from /proc/net/snmp.",
+		}
+		for _, proto := range []struct {
+			name string
+			data map[string]uint64
+		}{
+			{"IP", counters.SNMP.IP},
+			{"ICMP", counters.SNMP.ICMP},
+			{"ICMPMsg", counters.SNMP.ICMPMsg},
+			{"TCP", counters.SNMP.TCP},
+			{"UDP", counters.SNMP.UDP},
+			{"UDPLite", counters.SNMP.UDPLite},
+		} {
+			counterData := make(map[string]uint64)
+			gaugeData := make(map[string]uint64)
+			for name, value := range proto.data {
+				if snmpGauges[name] {
+					gaugeData[name] = value
+				} else {
+					counterData[name] = value
+				}
+			}
+			addPromCounterMap(snmp, proto.name, counterData)
+			addPromCounterMap(snmpGauge, proto.name, gaugeData)
+		}
+
+		families = append(families, netstat, snmp, snmpGauge)
+	}
+
+	// Cache
+	cacheHits := &promFamily{
+		name:    "cache_hits",
+		help:    "Snapshots of a collector reused from the cache.",
+		counter: true,
+	}
+	cacheMisses := &promFamily{
+		name:    "cache_misses",
+		help:    "Snapshots of a collector that had to be collected.",
+		counter: true,
+	}
+	for _, name := range Collectors.Names() {
+		stats := Collectors.CacheStats(name)
+		cacheHits.add(float64(stats.Hits()), "collector", name)
+		cacheMisses.add(float64(stats.Misses()), "collector", name)
+	}
+	families = append(families, cacheHits, cacheMisses)
+
+	return families
+}
+
+// addPromCounterMap adds one sample per entry of data, sorted by name so the
+// output is stable between scrapes. Values are signed, like the Counter
+// type, as a few settings like Tcp MaxConn are -1.
+func addPromCounterMap(family *promFamily, protocol string, data map[string]uint64) {
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		family.add(float64(int64(data[name])), "protocol", protocol, "name", name)
+	}
+}
+
+func writePromFamily(w *bufio.Writer, family *promFamily, openMetrics bool) {
+	if len(family.samples) == 0 {
+		return
+	}
+
+	name := promNamespace + "_" + family.name
+	sampleName := name
+	metricType := "gauge"
+	if family.counter {
+		metricType = "counter"
+		sampleName += "_total"
+		// The text format names the family after its samples, OpenMetrics
+		// drops the suffix
+		if !openMetrics {
+			name = sampleName
+		}
+	}
+
+	fmt.Fprintf(w, "# HELP %s %s\n", name, escapePromHelp(family.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType)
+
+	for _, sample := range family.samples {
+		w.WriteString(sampleName)
+		if len(sample.labels) > 0 {
+			w.WriteByte('{')
+			for i := 0; i+1 < len(sample.labels); i += 2 {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				fmt.Fprintf(w, "%s=\"%s\"", sample.labels[i], escapePromLabel(sample.labels[i+1]))
+			}
+			w.WriteByte('}')
+		}
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatFloat(sample.value, 'g', -1, 64))
+		w.WriteByte('\n')
+	}
+}
+
+func escapePromHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapePromLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
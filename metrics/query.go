@@ -1,13 +1,8 @@
 package metrics
 
 import (
-	"runtime"
-
-	"github.com/elastic/go-sysinfo"
 	"github.com/elastic/go-sysinfo/types"
 	"github.com/graphql-go/graphql"
-	"github.com/shirou/gopsutil/cpu"
-	"github.com/shirou/gopsutil/disk"
 )
 
 var (
@@ -16,6 +11,7 @@ var (
 
 func init() {
 	initTypes()
+	initSubscription()
 	initQuery()
 }
 
@@ -26,61 +22,91 @@ func initQuery() {
 			"host": &graphql.Field{
 				Type: hostType,
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					host, _ := sysinfo.Host()
-					return host.Info(), nil
+					return Collectors.Collect(p.Context, "host")
 				},
 			},
 			"os": &graphql.Field{
 				Type: osType,
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					host, _ := sysinfo.Host()
-					hostinfo := host.Info()
-					os := *hostinfo.OS
-					return os, nil
+					host, err := Collectors.Collect(p.Context, "host")
+					if err != nil {
+						return nil, err
+					}
+					if hostinfo := host.(types.HostInfo); hostinfo.OS != nil {
+						return *hostinfo.OS, nil
+					}
+					return nil, nil
 				},
 			},
 			"cpu": &graphql.Field{
 				Type: cpuType,
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					host, _ := sysinfo.Host()
-					var cpuObj CPU
-
-					// CPU Load
-					if load, ok := host.(types.LoadAverage); ok {
-						cpuObj.Load, _ = load.LoadAverage()
-					}
-
-					// CPU timers
-					cpuObj.Time, _ = host.CPUTime()
-
-					cpuObj.Info, _ = cpu.Info()
-
-					// CPU Number of cores
-					cpuObj.CoreCount = int16(runtime.NumCPU())
-
-					return cpuObj, nil
+					return Collectors.Collect(p.Context, "cpu")
 				},
 			},
 			"memory": &graphql.Field{
 				Type: memoryType,
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					host, _ := sysinfo.Host()
-					memory, _ := host.Memory()
-					return *memory, nil
+					return Collectors.Collect(p.Context, "memory")
 				},
 			},
 			"network": &graphql.Field{
 				Type: networkType,
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					var network Network
-					host, _ := sysinfo.Host()
+					return Collectors.Collect(p.Context, "network")
+				},
+			},
+			"process": &graphql.Field{
+				Type: processType,
+				Args: graphql.FieldConfigArgument{
+					"pid": &graphql.ArgumentConfig{
+						Type:        graphql.NewNonNull(graphql.Int),
+						Description: "ID of the process",
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := Collectors.check("processes"); err != nil {
+						return nil, err
+					}
+
+					proc, err := getProcess(p.Args["pid"].(int))
+					if err != nil {
+						return nil, collectorError("processes", err)
+					}
 
-					if n, ok := host.(types.NetworkCounters); ok {
-						netcounter, _ := n.NetworkCounters()
-						network.Network = *netcounter
+					procObj, err := newProcess(proc)
+					if err != nil {
+						return nil, collectorError("processes", err)
+					}
+					return procObj, nil
+				},
+			},
+			"processes": &graphql.Field{
+				Type: graphql.NewList(processType),
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{
+						Type:        graphql.String,
+						Description: "Only processes whose name or command line contains this string",
+					},
+					"sortBy": &graphql.ArgumentConfig{
+						Type:         processSortEnum,
+						Description:  "Either PID, NAME, CPU, MEMORY or START_TIME",
+						DefaultValue: "PID",
+					},
+					"limit": &graphql.ArgumentConfig{
+						Type:        graphql.Int,
+						Description: "Maximum number of processes returned",
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					processes, err := Collectors.Collect(p.Context, "processes")
+					if err != nil {
+						return nil, err
 					}
 
-					return network, nil
+					filter, _ := p.Args["filter"].(string)
+					limit, _ := p.Args["limit"].(int)
+					return selectProcesses(processes.([]Process), filter, p.Args["sortBy"].(string), limit), nil
 				},
 			},
 			"disk": &graphql.Field{
@@ -92,29 +118,83 @@ func initQuery() {
 					},
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					var diskObj Disk
-					diskObj.Partitions, _ = disk.Partitions(false)
+					diskObj, err := Collectors.Collect(p.Context, "disk")
+					if err != nil {
+						return nil, err
+					}
 
-					if p.Args["device"] != nil {
-						diskObj.Partitions, _ = disk.Partitions(false)
-						for _, partition := range diskObj.Partitions {
-							if partition.Device == p.Args["device"] {
-								diskObj.Partitions = []disk.PartitionStat{partition}
-								break
-							}
-						}
+					device, _ := p.Args["device"].(string)
+					narrowed, err := diskObj.(Disk).narrow(device)
+					if err != nil {
+						return nil, collectorError("disk", err)
+					}
+					return narrowed, nil
+				},
+			},
+			"pressure": &graphql.Field{
+				Type: pressureType,
+				Args: graphql.FieldConfigArgument{
+					"cgroup": &graphql.ArgumentConfig{
+						Type:        graphql.String,
+						Description: "Path of a cgroup v2 (e.g. /system.slice), defaults to the whole host",
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					cgroup, ok := p.Args["cgroup"].(string)
+					if !ok {
+						return Collectors.Collect(p.Context, "pressure")
+					}
 
-						tempUsageStat, _ := disk.Usage(diskObj.Partitions[0].Mountpoint)
-						diskObj.UsageStat = *tempUsageStat
+					if err := Collectors.check("pressure"); err != nil {
+						return nil, err
+					}
+					pressure, err := cgroupPressure(cgroup)
+					if err != nil {
+						return nil, collectorError("pressure", err)
+					}
+					return pressure, nil
+				},
+			},
+			"cgroups": &graphql.Field{
+				Type: cgroupType,
+				Args: graphql.FieldConfigArgument{
+					"path": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						Description:  "Path of the cgroup in the cgroup v2 hierarchy (e.g. /system.slice)",
+						DefaultValue: "/",
+					},
+					"recursive": &graphql.ArgumentConfig{
+						Type:         graphql.Boolean,
+						Description:  "Also list every cgroup below it",
+						DefaultValue: false,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					tree, err := Collectors.Collect(p.Context, "cgroups")
+					if err != nil {
+						return nil, err
 					}
 
-					return diskObj, nil
+					path, _ := p.Args["path"].(string)
+					recursive, _ := p.Args["recursive"].(bool)
+					cgroup, err := tree.(*cgroupTree).find(path, recursive)
+					if err != nil {
+						return nil, collectorError("cgroups", err)
+					}
+					return cgroup, nil
 				},
 			},
 		},
 	})
 
-	MetricsSchema, _ = graphql.NewSchema(graphql.SchemaConfig{
-		Query: queryType,
+	var err error
+	MetricsSchema, err = graphql.NewSchema(graphql.SchemaConfig{
+		Query:        queryType,
+		Subscription: subscriptionType,
 	})
+	if err != nil {
+		panic(err)
+	}
+	authorizeResolvers(MetricsSchema)
+	recoverResolvers(MetricsSchema)
 }
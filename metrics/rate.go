@@ -0,0 +1,274 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/elastic/go-sysinfo/types"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/net"
+)
+
+var errNotEnoughSamples = unavailableError("NOT_ENOUGH_SAMPLES", "not enough samples collected yet")
+
+// parseWindow parses the optional window argument of the rate fields.
+func parseWindow(window interface{}) (time.Duration, error) {
+	if window == nil {
+		return 0, nil
+	}
+	return time.ParseDuration(window.(string))
+}
+
+// CPUUtilization is the share of CPU time spent in each mode between two
+// samples, in percent.
+type CPUUtilization struct {
+	User    float64
+	System  float64
+	Idle    float64
+	IOWait  float64
+	IRQ     float64
+	Nice    float64
+	SoftIRQ float64
+	Steal   float64
+}
+
+// Busy is the share of time not spent idle or waiting for IO.
+func (u CPUUtilization) Busy() float64 {
+	return 100 - u.Idle - u.IOWait
+}
+
+// DiskIORate is the throughput and latency of a device between two samples.
+type DiskIORate struct {
+	ReadBytes   float64 // Per second
+	WriteBytes  float64 // Per second
+	ReadOps     float64 // Per second
+	WriteOps    float64 // Per second
+	ReadAwait   float64 // Milliseconds
+	WriteAwait  float64 // Milliseconds
+	Await       float64 // Milliseconds
+	Utilization float64 // Percent
+}
+
+// InterfaceRate is the per second traffic of a network interface between two
+// samples.
+type InterfaceRate struct {
+	RxBytes   float64
+	TxBytes   float64
+	RxPackets float64
+	TxPackets float64
+	RxErrors  float64
+	TxErrors  float64
+	RxDrops   float64
+	TxDrops   float64
+}
+
+// counterDelta returns how much a cumulative counter increased from prev to
+// cur. A counter lower than before either wrapped around, when it was close
+// to the 32 bit limit, or was reset to zero, in which case cur is all that
+// is known to have happened since.
+func counterDelta(prev, cur uint64) uint64 {
+	if cur >= prev {
+		return cur - prev
+	}
+	if prev <= math.MaxUint32 && prev > math.MaxUint32/2 {
+		return math.MaxUint32 - prev + cur + 1
+	}
+	return cur
+}
+
+// rebooted tells whether the host restarted between two samples, making
+// every cumulative counter start over.
+func rebooted(prev, last Sample) bool {
+	return !prev.BootTime.IsZero() && !last.BootTime.IsZero() &&
+		!prev.BootTime.Equal(last.BootTime)
+}
+
+// cpuUtilization computes the CPU utilization over window from the samples
+// kept by History.
+func cpuUtilization(window time.Duration) (CPUUtilization, error) {
+	prev, last, ok := History.Pair(window)
+	if !ok {
+		return CPUUtilization{}, errNotEnoughSamples
+	}
+
+	before := prev.CPU.Time
+	if rebooted(prev, last) {
+		before = types.CPUTimes{}
+	}
+	return utilizationBetween(before, last.CPU.Time), nil
+}
+
+// utilizationBetween computes the share of time spent in each mode between
+// two readings of the CPU timers. Timers going backwards start over from
+// zero.
+func utilizationBetween(before, after types.CPUTimes) CPUUtilization {
+	if after.Total() < before.Total() {
+		before = types.CPUTimes{}
+	}
+
+	total := float64(after.Total() - before.Total())
+	if total <= 0 {
+		return CPUUtilization{}
+	}
+
+	percent := func(before, after time.Duration) float64 {
+		return 100 * float64(after-before) / total
+	}
+
+	return CPUUtilization{
+		User:    percent(before.User, after.User),
+		System:  percent(before.System, after.System),
+		Idle:    percent(before.Idle, after.Idle),
+		IOWait:  percent(before.IOWait, after.IOWait),
+		IRQ:     percent(before.IRQ, after.IRQ),
+		Nice:    percent(before.Nice, after.Nice),
+		SoftIRQ: percent(before.SoftIRQ, after.SoftIRQ),
+		Steal:   percent(before.Steal, after.Steal),
+	}
+}
+
+// networkRate computes the per second rate of a network counter over window
+// from the samples kept by History.
+func networkRate(protocol, counter string, window time.Duration) (float64, error) {
+	prev, last, ok := History.Pair(window)
+	if !ok {
+		return 0, errNotEnoughSamples
+	}
+
+	elapsed := last.Timestamp.Sub(prev.Timestamp).Seconds()
+	if elapsed <= 0 {
+		return 0, nil
+	}
+
+	cur := last.Network.counters(protocol)[counter]
+	if rebooted(prev, last) {
+		return float64(cur) / elapsed, nil
+	}
+	return float64(counterDelta(prev.Network.counters(protocol)[counter], cur)) / elapsed, nil
+}
+
+// counters returns the SNMP or netstat counters of protocol.
+func (n Network) counters(protocol string) map[string]uint64 {
+	switch protocol {
+	case "IP":
+		return n.Network.SNMP.IP
+	case "ICMP":
+		return n.Network.SNMP.ICMP
+	case "ICMPMsg":
+		return n.Network.SNMP.ICMPMsg
+	case "TCP":
+		return n.Network.SNMP.TCP
+	case "UDP":
+		return n.Network.SNMP.UDP
+	case "UDPLite":
+		return n.Network.SNMP.UDPLite
+	case "IPExt":
+		return n.Network.Netstat.IPExt
+	case "TCPExt":
+		return n.Network.Netstat.TCPExt
+	}
+	return nil
+}
+
+// counterList returns the counters of the given protocols, sorted by
+// protocol and name, or only the one called name when it is set.
+func (n Network) counterList(protocols []string, name string) []Counter {
+	list := []Counter{}
+	for _, protocol := range protocols {
+		counters := n.counters(protocol)
+		if name != "" {
+			if value, ok := counters[name]; ok {
+				list = append(list, Counter{Protocol: protocol, Name: name, Value: value})
+			}
+			continue
+		}
+
+		names := make([]string, 0, len(counters))
+		for counter := range counters {
+			names = append(names, counter)
+		}
+		sort.Strings(names)
+		for _, counter := range names {
+			list = append(list, Counter{Protocol: protocol, Name: counter, Value: counters[counter]})
+		}
+	}
+	return list
+}
+
+// diskIORate computes the throughput and latency of device over window from
+// the samples kept by History.
+func diskIORate(device string, window time.Duration) (DiskIORate, error) {
+	prev, last, ok := History.Pair(window)
+	if !ok {
+		return DiskIORate{}, errNotEnoughSamples
+	}
+
+	elapsed := last.Timestamp.Sub(prev.Timestamp).Seconds()
+	if elapsed <= 0 {
+		return DiskIORate{}, nil
+	}
+
+	before, after := prev.IO[device], last.IO[device]
+	if rebooted(prev, last) {
+		before = disk.IOCountersStat{}
+	}
+
+	reads := counterDelta(before.ReadCount, after.ReadCount)
+	writes := counterDelta(before.WriteCount, after.WriteCount)
+	readTime := counterDelta(before.ReadTime, after.ReadTime)
+	writeTime := counterDelta(before.WriteTime, after.WriteTime)
+
+	rate := DiskIORate{
+		ReadBytes:   float64(counterDelta(before.ReadBytes, after.ReadBytes)) / elapsed,
+		WriteBytes:  float64(counterDelta(before.WriteBytes, after.WriteBytes)) / elapsed,
+		ReadOps:     float64(reads) / elapsed,
+		WriteOps:    float64(writes) / elapsed,
+		Utilization: math.Min(100, float64(counterDelta(before.IoTime, after.IoTime))/(elapsed*10)),
+	}
+	if reads > 0 {
+		rate.ReadAwait = float64(readTime) / float64(reads)
+	}
+	if writes > 0 {
+		rate.WriteAwait = float64(writeTime) / float64(writes)
+	}
+	if reads+writes > 0 {
+		rate.Await = float64(readTime+writeTime) / float64(reads+writes)
+	}
+
+	return rate, nil
+}
+
+// interfaceRate computes the traffic of the network interface called name
+// over window from the samples kept by History.
+func interfaceRate(name string, window time.Duration) (InterfaceRate, error) {
+	prev, last, ok := History.Pair(window)
+	if !ok {
+		return InterfaceRate{}, errNotEnoughSamples
+	}
+
+	elapsed := last.Timestamp.Sub(prev.Timestamp).Seconds()
+	if elapsed <= 0 {
+		return InterfaceRate{}, nil
+	}
+
+	before, after := prev.NIC[name], last.NIC[name]
+	if rebooted(prev, last) {
+		before = net.IOCountersStat{}
+	}
+
+	perSecond := func(before, after uint64) float64 {
+		return float64(counterDelta(before, after)) / elapsed
+	}
+
+	return InterfaceRate{
+		RxBytes:   perSecond(before.BytesRecv, after.BytesRecv),
+		TxBytes:   perSecond(before.BytesSent, after.BytesSent),
+		RxPackets: perSecond(before.PacketsRecv, after.PacketsRecv),
+		TxPackets: perSecond(before.PacketsSent, after.PacketsSent),
+		RxErrors:  perSecond(before.Errin, after.Errin),
+		TxErrors:  perSecond(before.Errout, after.Errout),
+		RxDrops:   perSecond(before.Dropin, after.Dropin),
+		TxDrops:   perSecond(before.Dropout, after.Dropout),
+	}, nil
+}
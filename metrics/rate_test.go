@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-sysinfo/types"
+)
+
+func TestCounterDelta(t *testing.T) {
+	tests := []struct {
+		name      string
+		prev, cur uint64
+		want      uint64
+	}{
+		{"increase", 10, 15, 5},
+		{"unchanged", 42, 42, 0},
+		{"32 bit wrap", math.MaxUint32 - 4, 5, 10},
+		{"32 bit wrap to zero", math.MaxUint32, 0, 1},
+		{"reset", 1000, 10, 10},
+		{"reset to zero", 1000, 0, 0},
+		{"64 bit counter reset", math.MaxUint32 + 100, 5, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := counterDelta(tt.prev, tt.cur); got != tt.want {
+				t.Errorf("counterDelta(%d, %d) = %d, want %d", tt.prev, tt.cur, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUtilizationBetween(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after types.CPUTimes
+		want          CPUUtilization
+	}{
+		{
+			name:   "split",
+			before: types.CPUTimes{User: 10 * time.Second, System: 10 * time.Second, Idle: 80 * time.Second},
+			after:  types.CPUTimes{User: 60 * time.Second, System: 35 * time.Second, Idle: 100 * time.Second, IOWait: 5 * time.Second},
+			want:   CPUUtilization{User: 50, System: 25, Idle: 20, IOWait: 5},
+		},
+		{
+			name:   "every mode",
+			before: types.CPUTimes{},
+			after: types.CPUTimes{
+				User: time.Second, System: time.Second, Idle: time.Second, IOWait: time.Second,
+				IRQ: time.Second, Nice: time.Second, SoftIRQ: time.Second, Steal: time.Second,
+			},
+			want: CPUUtilization{User: 12.5, System: 12.5, Idle: 12.5, IOWait: 12.5, IRQ: 12.5, Nice: 12.5, SoftIRQ: 12.5, Steal: 12.5},
+		},
+		{
+			name:   "no time elapsed",
+			before: types.CPUTimes{User: time.Second, Idle: time.Second},
+			after:  types.CPUTimes{User: time.Second, Idle: time.Second},
+			want:   CPUUtilization{},
+		},
+		{
+			name:   "timers reset",
+			before: types.CPUTimes{User: time.Hour, Idle: time.Hour},
+			after:  types.CPUTimes{User: 3 * time.Second, Idle: time.Second},
+			want:   CPUUtilization{User: 75, Idle: 25},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := utilizationBetween(tt.before, tt.after); got != tt.want {
+				t.Errorf("utilizationBetween() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCPUUtilizationBusy(t *testing.T) {
+	u := CPUUtilization{User: 50, System: 25, Idle: 20, IOWait: 5}
+	if got := u.Busy(); got != 75 {
+		t.Errorf("Busy() = %v, want 75", got)
+	}
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Scalars for the values the built-in Int, a signed 32 bit integer, cannot
+// hold. They are serialized as JSON numbers, which JavaScript clients only
+// read exactly up to 2^53.
+var (
+	Int64Scalar = graphql.NewScalar(graphql.ScalarConfig{
+		Name:        "Int64",
+		Description: "Signed 64 bit integer",
+		Serialize:   serializeInt64,
+		ParseValue:  serializeInt64,
+		ParseLiteral: func(valueAST ast.Value) interface{} {
+			if value, ok := valueAST.(*ast.IntValue); ok {
+				if i, err := strconv.ParseInt(value.Value, 10, 64); err == nil {
+					return i
+				}
+			}
+			return nil
+		},
+	})
+
+	UInt64Scalar = graphql.NewScalar(graphql.ScalarConfig{
+		Name:         "UInt64",
+		Description:  "Unsigned 64 bit integer, used for counters",
+		Serialize:    serializeUInt64,
+		ParseValue:   serializeUInt64,
+		ParseLiteral: parseUInt64Literal,
+	})
+
+	BytesScalar = graphql.NewScalar(graphql.ScalarConfig{
+		Name:        "Bytes",
+		Description: "Amount of bytes, as an unsigned 64 bit integer, or a number in the unit asked for",
+		Serialize: func(value interface{}) interface{} {
+			if f, ok := value.(float64); ok {
+				return f
+			}
+			return serializeUInt64(value)
+		},
+		ParseValue:   serializeUInt64,
+		ParseLiteral: parseUInt64Literal,
+	})
+
+	DurationScalar = graphql.NewScalar(graphql.ScalarConfig{
+		Name:        "Duration",
+		Description: "Duration in seconds, or in the unit asked for. Inputs may also be written as Go durations (e.g. 1m30s)",
+		Serialize: func(value interface{}) interface{} {
+			switch value := value.(type) {
+			case time.Duration:
+				return value.Seconds()
+			case float64, string:
+				return value
+			}
+			return nil
+		},
+		ParseValue: func(value interface{}) interface{} {
+			switch value := value.(type) {
+			case string:
+				if d, err := time.ParseDuration(value); err == nil {
+					return d
+				}
+			case float64:
+				return time.Duration(value * float64(time.Second))
+			case int:
+				return time.Duration(value) * time.Second
+			}
+			return nil
+		},
+		ParseLiteral: func(valueAST ast.Value) interface{} {
+			switch value := valueAST.(type) {
+			case *ast.StringValue:
+				if d, err := time.ParseDuration(value.Value); err == nil {
+					return d
+				}
+			case *ast.IntValue, *ast.FloatValue:
+				if s, err := strconv.ParseFloat(value.GetValue().(string), 64); err == nil {
+					return time.Duration(s * float64(time.Second))
+				}
+			}
+			return nil
+		},
+	})
+)
+
+func serializeInt64(value interface{}) interface{} {
+	switch value := value.(type) {
+	case int64:
+		return value
+	case int:
+		return int64(value)
+	case int32:
+		return int64(value)
+	case uint64:
+		if value <= math.MaxInt64 {
+			return int64(value)
+		}
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) <= math.MaxInt64 {
+			return int64(value)
+		}
+	}
+	return nil
+}
+
+func serializeUInt64(value interface{}) interface{} {
+	switch value := value.(type) {
+	case uint64:
+		return value
+	case uint32:
+		return uint64(value)
+	case int64:
+		if value >= 0 {
+			return uint64(value)
+		}
+	case int:
+		if value >= 0 {
+			return uint64(value)
+		}
+	case float64:
+		if value >= 0 && value == math.Trunc(value) && value <= math.MaxUint64 {
+			return uint64(value)
+		}
+	}
+	return nil
+}
+
+func parseUInt64Literal(valueAST ast.Value) interface{} {
+	if value, ok := valueAST.(*ast.IntValue); ok {
+		if u, err := strconv.ParseUint(value.Value, 10, 64); err == nil {
+			return u
+		}
+	}
+	return nil
+}
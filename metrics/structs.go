@@ -1,9 +1,12 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/elastic/go-sysinfo/types"
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/net"
 )
 
 type CPU struct {
@@ -11,21 +14,113 @@ type CPU struct {
 	Time      types.CPUTimes
 	Load      *types.LoadAverageInfo
 	CoreCount int16
+	Cores     []Core
+}
+
+// Core is a logical CPU. Frequencies are in MHz, they and the topology are
+// nil when sysfs does not expose them.
+type Core struct {
+	ID           int
+	Time         types.CPUTimes
+	Frequency    *float64
+	MinFrequency *float64
+	MaxFrequency *float64
+	Socket       *int
+	CoreID       *int
+	Node         *int
+}
+
+// Memory is the memory info of go-sysinfo along with the counters of
+// /proc/vmstat.
+type Memory struct {
+	types.HostMemoryInfo
+	VMStat map[string]uint64
+}
+
+type Swap struct {
+	Total  uint64
+	Used   uint64
+	Free   uint64
+	Cached uint64
+	In     uint64 // Pages swapped in since boot
+	Out    uint64 // Pages swapped out since boot
+}
+
+// Hugepages counts the pages of the default huge page size.
+type Hugepages struct {
+	Total    uint64
+	Free     uint64
+	Reserved uint64
+	Surplus  uint64
+	PageSize uint64
+	Hugetlb  uint64 // Memory used by huge pages of all sizes
+}
+
+// Stat is a single value of a file like /proc/meminfo or /proc/vmstat.
+type Stat struct {
+	Name  string
+	Value uint64
 }
 
 type Disk struct {
-	Partitions     []disk.PartitionStat
-	UsageStat      disk.UsageStat
+	Device     string
+	Partitions []disk.PartitionStat
+	UsageStat  disk.UsageStat
+	usageErr   error // Why UsageStat could not be read
+}
+
+type Partition struct {
+	disk.PartitionStat
+	UsageStat disk.UsageStat
+	Virtual   bool  // Not backed by a device, like proc or tmpfs
+	usageErr  error // Why UsageStat could not be read
 }
 
 type Network struct {
 	Network types.NetworkCountersInfo
 }
 
+// Counter is a single SNMP or netstat counter.
+type Counter struct {
+	Protocol string
+	Name     string
+	Value    uint64
+}
+
+type Interface struct {
+	Name         string
+	MTU          int
+	HardwareAddr string
+	Flags        []string
+	Addrs        []string
+	Speed        *int // Link speed in Mbps, unknown for virtual interfaces
+	OperState    string
+	Counters     net.IOCountersStat
+}
+
+type Connection struct {
+	Protocol      string
+	LocalAddress  string // Path of Unix sockets
+	LocalPort     int
+	RemoteAddress string
+	RemotePort    int
+	State         string
+	UID           int
+	Inode         uint64
+	PID           int // Zero when the owner is unknown
+}
+
 type Process struct {
-	PID         int     `json:"pid"`
-	Name        string  `json:"name"`
-	CPUUsage    float64 `json:"cpuUsage"`
-	MemoryUsage uint64  `json:"memoryUsage"`
-	StartTime   int64   `json:"startTime"`
+	PID        int       `json:"pid"`
+	PPID       int       `json:"ppid"`
+	Name       string    `json:"name"`
+	Exe        string    `json:"exe"`
+	Cmdline    []string  `json:"cmdline"`
+	User       string    `json:"user"`
+	State      string    `json:"state"`
+	CPUUsage   float64   `json:"cpuUsage"`
+	RSS        uint64    `json:"rss"`
+	VMS        uint64    `json:"vms"`
+	NumThreads int32     `json:"numThreads"`
+	StartTime  time.Time `json:"startTime"`
 }
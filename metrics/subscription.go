@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	DefaultSubscriptionInterval = time.Second
+	MinSubscriptionInterval     = 100 * time.Millisecond
+)
+
+var subscriptionType *graphql.Object
+
+// tick emits the current time right away and then every interval until ctx
+// is done. Ticks are dropped while the subscriber is still busy with the
+// previous one, so slow clients get fewer updates instead of a backlog.
+func tick(ctx context.Context, interval time.Duration) chan interface{} {
+	ticks := make(chan interface{})
+
+	go func() {
+		defer close(ticks)
+
+		select {
+		case ticks <- time.Now():
+		case <-ctx.Done():
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				select {
+				case ticks <- now:
+				default:
+				}
+			}
+		}
+	}()
+
+	return ticks
+}
+
+// subscribeEvery is the subscribe function shared by every subscription
+// field, it ticks at the requested interval.
+func subscribeEvery(p graphql.ResolveParams) (interface{}, error) {
+	interval := DefaultSubscriptionInterval
+	if p.Args["interval"] != nil {
+		var err error
+		if interval, err = time.ParseDuration(p.Args["interval"].(string)); err != nil {
+			return nil, err
+		}
+	}
+
+	if interval < MinSubscriptionInterval {
+		return nil, fmt.Errorf("interval must be at least %s", MinSubscriptionInterval)
+	}
+
+	// Cached snapshots would send the same values over and over when the
+	// interval is shorter than their TTL
+	setMaxAge(p.Context, interval/2)
+	return tick(p.Context, interval), nil
+}
+
+func intervalArgument() *graphql.ArgumentConfig {
+	return &graphql.ArgumentConfig{
+		Type:         graphql.String,
+		Description:  "Time between two updates (e.g. 1s, 500ms)",
+		DefaultValue: DefaultSubscriptionInterval.String(),
+	}
+}
+
+func initSubscription() {
+	subscriptionType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"cpu": &graphql.Field{
+				Type: cpuType,
+				Args: graphql.FieldConfigArgument{
+					"interval": intervalArgument(),
+				},
+				Subscribe: subscribeEvery,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return Collectors.Collect(p.Context, "cpu")
+				},
+			},
+			"memory": &graphql.Field{
+				Type: memoryType,
+				Args: graphql.FieldConfigArgument{
+					"interval": intervalArgument(),
+				},
+				Subscribe: subscribeEvery,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return Collectors.Collect(p.Context, "memory")
+				},
+			},
+			"network": &graphql.Field{
+				Type: networkType,
+				Args: graphql.FieldConfigArgument{
+					"interval": intervalArgument(),
+				},
+				Subscribe: subscribeEvery,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return Collectors.Collect(p.Context, "network")
+				},
+			},
+			"disk": &graphql.Field{
+				Type: diskType,
+				Args: graphql.FieldConfigArgument{
+					"device": &graphql.ArgumentConfig{
+						Type:        graphql.String,
+						Description: "Name of the device",
+					},
+					"interval": intervalArgument(),
+				},
+				Subscribe: subscribeEvery,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					diskObj, err := Collectors.Collect(p.Context, "disk")
+					if err != nil {
+						return nil, err
+					}
+
+					device, _ := p.Args["device"].(string)
+					narrowed, err := diskObj.(Disk).narrow(device)
+					if err != nil {
+						return nil, collectorError("disk", err)
+					}
+					return narrowed, nil
+				},
+			},
+		},
+	})
+}
@@ -1,86 +1,74 @@
 package metrics
 
 import (
-	"reflect"
+	"context"
+	"errors"
+	"io/fs"
+	"slices"
+	"time"
 
 	"github.com/elastic/go-sysinfo/types"
 	"github.com/graphql-go/graphql"
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/disk"
 )
 
 var (
-	hostType    *graphql.Object
-	osType      *graphql.Object
-	cpuType     *graphql.Object
-	memoryType  *graphql.Object
-	diskType    *graphql.Object // TODO
-	networkType *graphql.Object
-	processType *graphql.Object
+	hostType     *graphql.Object
+	osType       *graphql.Object
+	cpuType      *graphql.Object
+	memoryType   *graphql.Object
+	diskType     *graphql.Object // TODO
+	networkType  *graphql.Object
+	processType  *graphql.Object
+	pressureType *graphql.Object
+	cgroupType   *graphql.Object
+
+	processSortEnum *graphql.Enum
 )
 
 func initTypes() {
-	timeEnum := graphql.NewEnum(graphql.EnumConfig{
-		Name:        "Time",
-		Description: "One of the time windows for CPU usage",
+	protocolNetstatEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "NetstatProtocol",
+		Description: "Netstat protocol",
 		Values: graphql.EnumValueConfigMap{
-			"ONE": &graphql.EnumValueConfig{
-				Value: "One",
-			},
-			"FIVE": &graphql.EnumValueConfig{
-				Value: "Five",
+			"TCP": &graphql.EnumValueConfig{
+				Value: "TCP",
 			},
-			"FIFTEEN": &graphql.EnumValueConfig{
-				Value: "Fifteen",
+			"IP": &graphql.EnumValueConfig{
+				Value: "IP",
 			},
 		},
 	})
 
-	cpuTimeEnum := graphql.NewEnum(graphql.EnumConfig{
-		Name:        "Time",
-		Description: "One of the time windows for CPU usage",
+	protocolSNMPEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "SNMPProtocol",
+		Description: "SNMP protocol",
 		Values: graphql.EnumValueConfigMap{
-			"USER": &graphql.EnumValueConfig{
-				Value: "User",
-			},
-			"SYSTEM": &graphql.EnumValueConfig{
-				Value: "System",
-			},
-			"IDLE": &graphql.EnumValueConfig{
-				Value: "Idle",
-			},
-			"IOWAIT": &graphql.EnumValueConfig{
-				Value: "IOWait",
-			},
-			"IRQ": &graphql.EnumValueConfig{
-				Value: "IRQ",
-			},
-			"NICE": &graphql.EnumValueConfig{
-				Value: "Nice",
+			"IP": &graphql.EnumValueConfig{
+				Value: "IP",
 			},
-			"SOFTIRQ": &graphql.EnumValueConfig{
-				Value: "SoftIRQ",
+			"ICMP": &graphql.EnumValueConfig{
+				Value: "ICMP",
 			},
-			"STEAL": &graphql.EnumValueConfig{
-				Value: "Steal",
+			"ICMPMsg": &graphql.EnumValueConfig{
+				Value: "ICMPMsg",
 			},
-		},
-	})
-
-	protocolNetstatEnum := graphql.NewEnum(graphql.EnumConfig{
-		Name:        "Protocol",
-		Description: "Netstat protocol",
-		Values: graphql.EnumValueConfigMap{
 			"TCP": &graphql.EnumValueConfig{
 				Value: "TCP",
 			},
-			"IP": &graphql.EnumValueConfig{
-				Value: "IP",
+			"UDP": &graphql.EnumValueConfig{
+				Value: "UDP",
+			},
+			"UDPLite": &graphql.EnumValueConfig{
+				Value: "UDPLite",
 			},
 		},
 	})
 
-	protocolSNMPEnum := graphql.NewEnum(graphql.EnumConfig{
-		Name:        "Protocol",
-		Description: "Netstat protocol",
+	rateProtocolEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "RateProtocol",
+		Description: "SNMP or netstat protocol",
 		Values: graphql.EnumValueConfigMap{
 			"IP": &graphql.EnumValueConfig{
 				Value: "IP",
@@ -100,484 +88,574 @@ func initTypes() {
 			"UDPLite": &graphql.EnumValueConfig{
 				Value: "UDPLite",
 			},
+			"IPExt": &graphql.EnumValueConfig{
+				Value: "IPExt",
+			},
+			"TCPExt": &graphql.EnumValueConfig{
+				Value: "TCPExt",
+			},
 		},
 	})
 
-	modeEnum := graphql.NewEnum(graphql.EnumConfig{
-		Name:        "Mode",
-		Description: "Either in bytes or percentage",
+	connectionProtocolEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "ConnectionProtocol",
+		Description: "Socket protocol, TCP and UDP cover both IPv4 and IPv6",
 		Values: graphql.EnumValueConfigMap{
-			"BYTES": &graphql.EnumValueConfig{
-				Value: false,
+			"TCP": &graphql.EnumValueConfig{
+				Value: "tcp",
+			},
+			"TCP4": &graphql.EnumValueConfig{
+				Value: "tcp4",
+			},
+			"TCP6": &graphql.EnumValueConfig{
+				Value: "tcp6",
 			},
-			"PERCENT": &graphql.EnumValueConfig{
-				Value: true,
+			"UDP": &graphql.EnumValueConfig{
+				Value: "udp",
+			},
+			"UDP4": &graphql.EnumValueConfig{
+				Value: "udp4",
+			},
+			"UDP6": &graphql.EnumValueConfig{
+				Value: "udp6",
+			},
+			"UNIX": &graphql.EnumValueConfig{
+				Value: "unix",
 			},
 		},
 	})
 
-	cpuType = graphql.NewObject(graphql.ObjectConfig{
-		Name:        "CPU",
-		Description: "CPU info",
+	processSortEnum = graphql.NewEnum(graphql.EnumConfig{
+		Name:        "ProcessSort",
+		Description: "Order in which processes are listed",
+		Values: graphql.EnumValueConfigMap{
+			"PID": &graphql.EnumValueConfig{
+				Value: "PID",
+			},
+			"NAME": &graphql.EnumValueConfig{
+				Value: "Name",
+			},
+			"CPU": &graphql.EnumValueConfig{
+				Value: "CPU",
+			},
+			"MEMORY": &graphql.EnumValueConfig{
+				Value: "Memory",
+			},
+			"START_TIME": &graphql.EnumValueConfig{
+				Value: "StartTime",
+			},
+		},
+	})
+
+	cpuUtilizationType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "CPUUtilization",
+		Description: "Share of CPU time spent in each mode",
 		Fields: graphql.Fields{
-			"load": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Process hardware architecture",
-				Args: graphql.FieldConfigArgument{
-					"time": &graphql.ArgumentConfig{
-						Description: "There are three available time windows, 1, 5 and 15 minutes",
-						Type:        timeEnum,
-					},
-				},
+			"user": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Percentage of time spent in user mode",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if cpu, ok := p.Source.(CPU); ok {
-						if p.Args["time"] != nil {
-							return reflect.ValueOf(*cpu.Load).FieldByName(p.Args["time"].(string)), nil
-						}
-						return *cpu.Load, nil
+					if util, ok := p.Source.(CPUUtilization); ok {
+						return util.User, nil
 					}
 					return nil, nil
 				},
 			},
-			"times": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Timing stats for a process",
-				Args: graphql.FieldConfigArgument{
-					"stat": &graphql.ArgumentConfig{
-						Description: "There are three available time windows, 1, 5 and 15 minutes",
-						Type:        cpuTimeEnum,
-					},
-				},
+			"system": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Percentage of time spent in kernel mode",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if cpu, ok := p.Source.(CPU); ok {
-						if p.Args["stat"] != nil {
-							return reflect.ValueOf(cpu.Time).FieldByName(p.Args["stat"].(string)), nil
-						}
-						return cpu.Time.Total().String(), nil
+					if util, ok := p.Source.(CPUUtilization); ok {
+						return util.System, nil
 					}
 					return nil, nil
 				},
 			},
-			"cores": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.Int),
-				Description: "Number of cores in the CPU",
+			"idle": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Percentage of time spent idle",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if cpu, ok := p.Source.(CPU); ok {
-						return cpu.CoreCount, nil
+					if util, ok := p.Source.(CPUUtilization); ok {
+						return util.Idle, nil
 					}
 					return nil, nil
 				},
 			},
-			"info": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Overall CPU info",
+			"iowait": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Percentage of time spent waiting for IO",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if cpu, ok := p.Source.(CPU); ok {
-						return cpu.Info, nil
+					if util, ok := p.Source.(CPUUtilization); ok {
+						return util.IOWait, nil
 					}
 					return nil, nil
 				},
 			},
-		},
-	})
-
-	hostType = graphql.NewObject(graphql.ObjectConfig{
-		Name:        "Host",
-		Description: "Host info",
-		Fields: graphql.Fields{
-			"architecture": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Process hardware architecture",
+			"irq": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Percentage of time spent serving interrupts",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if host, ok := p.Source.(types.HostInfo); ok {
-						return host.Architecture, nil
+					if util, ok := p.Source.(CPUUtilization); ok {
+						return util.IRQ, nil
 					}
 					return nil, nil
 				},
 			},
-			"nativeArchitecture": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Native OS hardware architecture",
+			"nice": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Percentage of time spent running niced processes",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if host, ok := p.Source.(types.HostInfo); ok {
-						return host.NativeArchitecture, nil
+					if util, ok := p.Source.(CPUUtilization); ok {
+						return util.Nice, nil
 					}
 					return nil, nil
 				},
 			},
-			"bootTime": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.DateTime),
-				Description: "Host boot time",
+			"softirq": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Percentage of time spent serving soft interrupts",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if host, ok := p.Source.(types.HostInfo); ok {
-						return host.BootTime, nil
+					if util, ok := p.Source.(CPUUtilization); ok {
+						return util.SoftIRQ, nil
 					}
 					return nil, nil
 				},
 			},
-			"uptime": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Host uptime",
+			"steal": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Percentage of time stolen by the hypervisor",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if host, ok := p.Source.(types.HostInfo); ok {
-						return host.Uptime().String(), nil
+					if util, ok := p.Source.(CPUUtilization); ok {
+						return util.Steal, nil
 					}
 					return nil, nil
 				},
 			},
-			"containerized": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.Boolean),
-				Description: "Is the process containerized",
+			"busy": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Percentage of time not spent idle or waiting for IO",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if host, ok := p.Source.(types.HostInfo); ok && host.Containerized != nil {
-						return *host.Containerized, nil
+					if util, ok := p.Source.(CPUUtilization); ok {
+						return util.Busy(), nil
 					}
 					return nil, nil
 				},
 			},
-			"hostname": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Hostname",
+		},
+	})
+
+	loadAverageType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "LoadAverage",
+		Description: "Average number of runnable processes",
+		Fields: graphql.Fields{
+			"one": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Over the last minute",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if host, ok := p.Source.(types.HostInfo); ok {
-						return host.Hostname, nil
+					if load, ok := p.Source.(types.LoadAverageInfo); ok {
+						return load.One, nil
 					}
 					return nil, nil
 				},
 			},
-			"ips": &graphql.Field{
-				Type:        graphql.NewList(graphql.String),
-				Description: "List of all IPs",
+			"five": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Over the last 5 minutes",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if host, ok := p.Source.(types.HostInfo); ok {
-						return host.IPs, nil
+					if load, ok := p.Source.(types.LoadAverageInfo); ok {
+						return load.Five, nil
 					}
 					return nil, nil
 				},
 			},
-			"kernelVersion": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Kernel version",
+			"fifteen": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Over the last 15 minutes",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if host, ok := p.Source.(types.HostInfo); ok {
-						return host.KernelVersion, nil
+					if load, ok := p.Source.(types.LoadAverageInfo); ok {
+						return load.Fifteen, nil
 					}
 					return nil, nil
 				},
 			},
-			"macs": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.NewList(graphql.String)),
-				Description: "List of MAC addresses",
+		},
+	})
+
+	cpuTimesType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "CPUTimes",
+		Description: "Time spent by the CPU in each mode",
+		Fields: graphql.Fields{
+			"user": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Time spent in user mode",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if host, ok := p.Source.(types.HostInfo); ok {
-						return host.MACs, nil
+					if times, ok := p.Source.(types.CPUTimes); ok {
+						return times.User, nil
 					}
 					return nil, nil
 				},
-			},
-			"os": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "OS information",
+			}),
+			"system": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Time spent in kernel mode",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if host, ok := p.Source.(types.HostInfo); ok {
-						return host.OS.Name + " " + host.OS.Version, nil
+					if times, ok := p.Source.(types.CPUTimes); ok {
+						return times.System, nil
 					}
 					return nil, nil
 				},
-			},
-			"timezone": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "System timezone",
+			}),
+			"idle": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Time spent idle",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if host, ok := p.Source.(types.HostInfo); ok {
-						return host.Timezone, nil
+					if times, ok := p.Source.(types.CPUTimes); ok {
+						return times.Idle, nil
 					}
 					return nil, nil
 				},
-			},
-			"timezoneOffsetSec": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.Int),
-				Description: "Timezone offset (seconds from UTC)",
+			}),
+			"iowait": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Time spent waiting for IO",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if host, ok := p.Source.(types.HostInfo); ok {
-						return host.TimezoneOffsetSec, nil
+					if times, ok := p.Source.(types.CPUTimes); ok {
+						return times.IOWait, nil
 					}
 					return nil, nil
 				},
-			},
-			"uniqueID": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Unique ID of the host (optional)",
+			}),
+			"irq": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Time spent serving interrupts",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if host, ok := p.Source.(types.HostInfo); ok {
-						return host.UniqueID, nil
+					if times, ok := p.Source.(types.CPUTimes); ok {
+						return times.IRQ, nil
 					}
 					return nil, nil
 				},
-			},
+			}),
+			"nice": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Time spent in user mode with low priority",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if times, ok := p.Source.(types.CPUTimes); ok {
+						return times.Nice, nil
+					}
+					return nil, nil
+				},
+			}),
+			"softirq": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Time spent serving soft interrupts",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if times, ok := p.Source.(types.CPUTimes); ok {
+						return times.SoftIRQ, nil
+					}
+					return nil, nil
+				},
+			}),
+			"steal": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Time stolen by the hypervisor",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if times, ok := p.Source.(types.CPUTimes); ok {
+						return times.Steal, nil
+					}
+					return nil, nil
+				},
+			}),
+			"total": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Sum of all the modes",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if times, ok := p.Source.(types.CPUTimes); ok {
+						return times.Total(), nil
+					}
+					return nil, nil
+				},
+			}),
 		},
 	})
 
-	memoryType = graphql.NewObject(graphql.ObjectConfig{
-		Name:        "Memory",
-		Description: "Host memory info",
+	cpuInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "CPUInfo",
+		Description: "Logical CPU as described by /proc/cpuinfo",
 		Fields: graphql.Fields{
-			"total": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Total physical memory in bytes",
+			"cpu": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Logical CPU number",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if mem, ok := p.Source.(types.HostMemoryInfo); ok {
-						return mem.Total, nil
+					if info, ok := p.Source.(cpu.InfoStat); ok {
+						return info.CPU, nil
 					}
 					return nil, nil
 				},
 			},
-			"used": &graphql.Field{
+			"vendorId": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Total used memory in bytes",
+				Description: "Vendor",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if mem, ok := p.Source.(types.HostMemoryInfo); ok {
-						return mem.Used, nil
+					if info, ok := p.Source.(cpu.InfoStat); ok {
+						return info.VendorID, nil
 					}
 					return nil, nil
 				},
 			},
-			"available": &graphql.Field{
+			"family": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Amount of memory available without swapping in bytes",
+				Description: "CPU family",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if mem, ok := p.Source.(types.HostMemoryInfo); ok {
-						return mem.Available, nil
+					if info, ok := p.Source.(cpu.InfoStat); ok {
+						return info.Family, nil
 					}
 					return nil, nil
 				},
 			},
-			"free": &graphql.Field{
+			"model": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Amount of memory not used by the system in bytes",
+				Description: "Model number",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if mem, ok := p.Source.(types.HostMemoryInfo); ok {
-						return mem.Free, nil
+					if info, ok := p.Source.(cpu.InfoStat); ok {
+						return info.Model, nil
 					}
 					return nil, nil
 				},
 			},
-			"virtualTotal": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Total virtual memory in bytes",
+			"stepping": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Stepping",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if mem, ok := p.Source.(types.HostMemoryInfo); ok {
-						return mem.VirtualTotal, nil
+					if info, ok := p.Source.(cpu.InfoStat); ok {
+						return info.Stepping, nil
 					}
 					return nil, nil
 				},
 			},
-			"virtualUsed": &graphql.Field{
+			"physicalId": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Total used virtual memory in bytes",
+				Description: "Physical package",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if mem, ok := p.Source.(types.HostMemoryInfo); ok {
-						return mem.VirtualUsed, nil
+					if info, ok := p.Source.(cpu.InfoStat); ok {
+						return info.PhysicalID, nil
 					}
 					return nil, nil
 				},
 			},
-			"virtualFree": &graphql.Field{
+			"coreId": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Virtual memory that is not used in bytes",
+				Description: "Physical core",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if mem, ok := p.Source.(types.HostMemoryInfo); ok {
-						return mem.VirtualFree, nil
+					if info, ok := p.Source.(cpu.InfoStat); ok {
+						return info.CoreID, nil
 					}
 					return nil, nil
 				},
 			},
-		},
-	})
-
-	networkType = graphql.NewObject(graphql.ObjectConfig{
-		Name:        "Network",
-		Description: "Host network info",
-		Fields: graphql.Fields{
-			"netstat": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Netstat",
-				Args: graphql.FieldConfigArgument{
-					"protocol": &graphql.ArgumentConfig{
-						Description: "Either IP or TCP",
-						Type:        protocolNetstatEnum,
-					},
-					"counter": &graphql.ArgumentConfig{
-						Description: "Netstat counter",
-						Type:        graphql.String,
-					},
-				},
+			"cores": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Cores in the physical package",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if net, ok := p.Source.(Network); ok {
-						var data map[string]uint64
-						switch p.Args["protocol"] {
-						case "IP":
-							data = net.Network.Netstat.IPExt
-						case "TCP":
-							data = net.Network.Netstat.TCPExt
-						default:
-							return nil, nil
-						}
-
-						if p.Args["counter"] == nil {
-							return reflect.ValueOf(data).MapKeys(), nil
-						} else {
-							return data[p.Args["counter"].(string)], nil
-						}
+					if info, ok := p.Source.(cpu.InfoStat); ok {
+						return info.Cores, nil
 					}
 					return nil, nil
 				},
 			},
-			"snmp": &graphql.Field{
+			"modelName": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "SNMP",
-				Args: graphql.FieldConfigArgument{
-					"protocol": &graphql.ArgumentConfig{
-						Description: "Either IP, ICMP, ICMPMsg, TCP, UDP, UDPLite",
-						Type:        protocolSNMPEnum,
-					},
-					"counter": &graphql.ArgumentConfig{
-						Description: "Netstat counter",
-						Type:        graphql.String,
-					},
-				},
+				Description: "Model name",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if net, ok := p.Source.(Network); ok {
-						var data map[string]uint64
-						switch p.Args["protocol"] {
-						case "IP":
-							data = net.Network.SNMP.IP
-						case "ICMP":
-							data = net.Network.SNMP.ICMP
-						case "ICMPMsg":
-							data = net.Network.SNMP.ICMPMsg
-						case "TCP":
-							data = net.Network.SNMP.TCP
-						case "UDP":
-							data = net.Network.SNMP.UDP
-						case "UDPLite":
-							data = net.Network.SNMP.UDPLite
-						default:
-							return nil, nil
-						}
-
-						if p.Args["counter"] == nil {
-							return reflect.ValueOf(data).MapKeys(), nil
-						} else {
-							return data[p.Args["counter"].(string)], nil
-						}
+					if info, ok := p.Source.(cpu.InfoStat); ok {
+						return info.ModelName, nil
 					}
 					return nil, nil
 				},
 			},
-		},
-	})
-
-	osType = graphql.NewObject(graphql.ObjectConfig{
-		Name:        "OS",
-		Description: "Host OS info",
-		Fields: graphql.Fields{
-			"type": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "OS Type (one of linux, macos, unix, windows)",
+			"mhz": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Frequency in MHz",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if os, ok := p.Source.(types.OSInfo); ok {
-						return os.Type, nil
+					if info, ok := p.Source.(cpu.InfoStat); ok {
+						return info.Mhz, nil
 					}
 					return nil, nil
 				},
 			},
-			"family": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "OS Family (e.g. redhat, debian, freebsd, windows)",
+			"cacheSize": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Cache size in KB",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if os, ok := p.Source.(types.OSInfo); ok {
-						return os.Family, nil
+					if info, ok := p.Source.(cpu.InfoStat); ok {
+						return info.CacheSize, nil
 					}
 					return nil, nil
 				},
 			},
-			"platform": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "OS platform (e.g. centos, ubuntu, windows)",
+			"flags": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.String)),
+				Description: "CPU flags",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if os, ok := p.Source.(types.OSInfo); ok {
-						return os.Platform, nil
+					if info, ok := p.Source.(cpu.InfoStat); ok {
+						return info.Flags, nil
 					}
 					return nil, nil
 				},
 			},
-			"name": &graphql.Field{
+			"microcode": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "OS Name (e.g. Mac OS X, CentOS)",
+				Description: "Microcode revision",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if os, ok := p.Source.(types.OSInfo); ok {
-						return os.Name, nil
+					if info, ok := p.Source.(cpu.InfoStat); ok {
+						return info.Microcode, nil
 					}
 					return nil, nil
 				},
 			},
-			"version": &graphql.Field{
+		},
+	})
+
+	counterType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Counter",
+		Description: "SNMP or netstat counter",
+		Fields: graphql.Fields{
+			"protocol": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "OS version (e.g. 10.12.6)",
+				Description: "Protocol the counter belongs to, as accepted by network.rate",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if os, ok := p.Source.(types.OSInfo); ok {
-						return os.Version, nil
+					if counter, ok := p.Source.(Counter); ok {
+						return counter.Protocol, nil
 					}
 					return nil, nil
 				},
 			},
-			"major": &graphql.Field{
+			"name": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Major release version",
+				Description: "Counter name",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if os, ok := p.Source.(types.OSInfo); ok {
-						return os.Major, nil
+					if counter, ok := p.Source.(Counter); ok {
+						return counter.Name, nil
 					}
 					return nil, nil
 				},
 			},
-			"minor": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Minor release version",
+			"value": &graphql.Field{
+				Type:        graphql.NewNonNull(Int64Scalar),
+				Description: "Counter value, signed as a few like Tcp MaxConn are -1",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if os, ok := p.Source.(types.OSInfo); ok {
-						return os.Minor, nil
+					if counter, ok := p.Source.(Counter); ok {
+						return int64(counter.Value), nil
 					}
 					return nil, nil
 				},
 			},
-			"patch": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Patch release version",
+		},
+	})
+
+	coreType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Core",
+		Description: "Logical CPU",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Logical CPU number",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if os, ok := p.Source.(types.OSInfo); ok {
-						return os.Patch, nil
+					if core, ok := p.Source.(Core); ok {
+						return core.ID, nil
 					}
 					return nil, nil
 				},
 			},
-			"build": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Build (e.g. 16G1114)",
+			"times": &graphql.Field{
+				Type:        graphql.NewNonNull(cpuTimesType),
+				Description: "Time spent by the logical CPU in each mode",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if os, ok := p.Source.(types.OSInfo); ok {
-						return os.Build, nil
+					if core, ok := p.Source.(Core); ok {
+						return core.Time, nil
 					}
 					return nil, nil
 				},
 			},
-			"codename": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "OS codename (e.g. jessie)",
+			"utilization": &graphql.Field{
+				Type:        cpuUtilizationType,
+				Description: "Utilization computed between two background samples",
+				Args: graphql.FieldConfigArgument{
+					"window": &graphql.ArgumentConfig{
+						Description: "Time between the two samples (e.g. 1m), defaults to the last two",
+						Type:        graphql.String,
+					},
+				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if os, ok := p.Source.(types.OSInfo); ok {
-						return os.Codename, nil
+					if core, ok := p.Source.(Core); ok {
+						window, err := parseWindow(p.Args["window"])
+						if err != nil {
+							return nil, err
+						}
+						util, err := coreUtilization(core.ID, window)
+						if err != nil {
+							return nil, err
+						}
+						return util, nil
+					}
+					return nil, nil
+				},
+			},
+			"frequency": &graphql.Field{
+				Type:        graphql.Float,
+				Description: "Current frequency in MHz",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if core, ok := p.Source.(Core); ok && core.Frequency != nil {
+						return *core.Frequency, nil
+					}
+					return nil, nil
+				},
+			},
+			"minFrequency": &graphql.Field{
+				Type:        graphql.Float,
+				Description: "Minimum frequency in MHz",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if core, ok := p.Source.(Core); ok && core.MinFrequency != nil {
+						return *core.MinFrequency, nil
+					}
+					return nil, nil
+				},
+			},
+			"maxFrequency": &graphql.Field{
+				Type:        graphql.Float,
+				Description: "Maximum frequency in MHz",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if core, ok := p.Source.(Core); ok && core.MaxFrequency != nil {
+						return *core.MaxFrequency, nil
+					}
+					return nil, nil
+				},
+			},
+			"socket": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Physical package holding the logical CPU",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if core, ok := p.Source.(Core); ok && core.Socket != nil {
+						return *core.Socket, nil
+					}
+					return nil, nil
+				},
+			},
+			"coreId": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Physical core within the package",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if core, ok := p.Source.(Core); ok && core.CoreID != nil {
+						return *core.CoreID, nil
+					}
+					return nil, nil
+				},
+			},
+			"node": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "NUMA node",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if core, ok := p.Source.(Core); ok && core.Node != nil {
+						return *core.Node, nil
 					}
 					return nil, nil
 				},
@@ -585,134 +663,2472 @@ func initTypes() {
 		},
 	})
 
-	diskType = graphql.NewObject(graphql.ObjectConfig{
-		Name:        "Disk",
-		Description: "Disk info",
+	cpuType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "CPU",
+		Description: "CPU info",
 		Fields: graphql.Fields{
-			"devices": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.NewList(graphql.String)),
-				Description: "List of devices",
+			"load": &graphql.Field{
+				Type:        loadAverageType,
+				Description: "Load average, null when not supported",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cpu, ok := p.Source.(CPU); ok && cpu.Load != nil {
+						return *cpu.Load, nil
+					}
+					return nil, nil
+				},
+			},
+			"times": &graphql.Field{
+				Type:        graphql.NewNonNull(cpuTimesType),
+				Description: "Time spent by the CPU in each mode",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cpu, ok := p.Source.(CPU); ok {
+						return cpu.Time, nil
+					}
+					return nil, nil
+				},
+			},
+			"coreCount": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Number of logical CPUs",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cpu, ok := p.Source.(CPU); ok {
+						return cpu.CoreCount, nil
+					}
+					return nil, nil
+				},
+			},
+			"cores": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(coreType)),
+				Description: "Logical CPUs",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cpu, ok := p.Source.(CPU); ok {
+						return cpu.Cores, nil
+					}
+					return nil, nil
+				},
+			},
+			"utilization": &graphql.Field{
+				Type:        cpuUtilizationType,
+				Description: "CPU utilization computed between two background samples",
+				Args: graphql.FieldConfigArgument{
+					"window": &graphql.ArgumentConfig{
+						Description: "Time between the two samples (e.g. 1m), defaults to the last two",
+						Type:        graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					window, err := parseWindow(p.Args["window"])
+					if err != nil {
+						return nil, err
+					}
+					util, err := cpuUtilization(window)
+					if err != nil {
+						return nil, err
+					}
+					return util, nil
+				},
+			},
+			"info": &graphql.Field{
+				Type:        graphql.NewList(cpuInfoType),
+				Description: "Model and features of each logical CPU",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if disk, ok := p.Source.(Disk); ok {
-						var devices []string
-						for _, device := range disk.Partitions {
-							devices = append(devices, device.Device)
+					if cpuObj, ok := p.Source.(CPU); ok {
+						// Only read when asked for, /proc/cpuinfo is expensive
+						if cpuObj.Info == nil {
+							info, err := Collectors.Collect(p.Context, "cpuinfo")
+							if err != nil {
+								return nil, err
+							}
+							return info, nil
 						}
-						return devices, nil
+						return cpuObj.Info, nil
 					}
 					return nil, nil
 				},
 			},
-			"fstype": &graphql.Field{
+		},
+	})
+
+	hostType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Host",
+		Description: "Host info",
+		Fields: graphql.Fields{
+			"architecture": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Filesystem type",
+				Description: "Process hardware architecture",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if disk, ok := p.Source.(Disk); ok {
-						return disk.UsageStat.Fstype, nil
+					if host, ok := p.Source.(types.HostInfo); ok {
+						return host.Architecture, nil
 					}
 					return nil, nil
 				},
 			},
-			"mountpoint": &graphql.Field{
+			"nativeArchitecture": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Filesystem type",
+				Description: "Native OS hardware architecture",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if disk, ok := p.Source.(Disk); ok {
-						return disk.Partitions[0].Mountpoint, nil
+					if host, ok := p.Source.(types.HostInfo); ok {
+						return host.NativeArchitecture, nil
 					}
 					return nil, nil
 				},
 			},
-			"opts": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Filesystem type",
+			"bootTime": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.DateTime),
+				Description: "Host boot time",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if disk, ok := p.Source.(Disk); ok {
-						return disk.Partitions[0].Opts, nil
+					if host, ok := p.Source.(types.HostInfo); ok {
+						return host.BootTime, nil
 					}
 					return nil, nil
 				},
 			},
-			"total": &graphql.Field{
-				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Total storage space",
+			"uptime": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Host uptime",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if host, ok := p.Source.(types.HostInfo); ok {
+						return host.Uptime(), nil
+					}
+					return nil, nil
+				},
+			}),
+			"containerized": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "Is the process containerized",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if disk, ok := p.Source.(Disk); ok {
-						return disk.UsageStat.Total, nil
+					if host, ok := p.Source.(types.HostInfo); ok && host.Containerized != nil {
+						return *host.Containerized, nil
 					}
 					return nil, nil
 				},
 			},
-			"free": &graphql.Field{
+			"hostname": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Free storage space",
+				Description: "Hostname",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if host, ok := p.Source.(types.HostInfo); ok {
+						return host.Hostname, nil
+					}
+					return nil, nil
+				},
+			},
+			"ips": &graphql.Field{
+				Type:        graphql.NewList(graphql.String),
+				Description: "List of all IPs",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if disk, ok := p.Source.(Disk); ok {
-						return disk.UsageStat.Free, nil
+					if host, ok := p.Source.(types.HostInfo); ok {
+						return host.IPs, nil
 					}
 					return nil, nil
 				},
 			},
-			"used": &graphql.Field{
+			"kernelVersion": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Used storage space",
-				Args: graphql.FieldConfigArgument{
-					"mode": &graphql.ArgumentConfig{
-						Type:        modeEnum,
-						Description: "Either in BYTES or PERCENT",
-						DefaultValue: false,
-					},
+				Description: "Kernel version",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if host, ok := p.Source.(types.HostInfo); ok {
+						return host.KernelVersion, nil
+					}
+					return nil, nil
 				},
+			},
+			"macs": &graphql.Field{
+				Type:        graphql.NewList(graphql.String),
+				Description: "List of MAC addresses",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if disk, ok := p.Source.(Disk); ok {
-						if p.Args["mode"].(bool) {
-							return disk.UsageStat.UsedPercent, nil
-						}
-						return disk.UsageStat.Used, nil
+					if host, ok := p.Source.(types.HostInfo); ok {
+						return host.MACs, nil
 					}
 					return nil, nil
 				},
 			},
-			"inodestotal": &graphql.Field{
+			"os": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Total inodes",
+				Description: "OS information",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if disk, ok := p.Source.(Disk); ok {
-						return disk.UsageStat.InodesTotal, nil
+					if host, ok := p.Source.(types.HostInfo); ok {
+						return host.OS.Name + " " + host.OS.Version, nil
 					}
 					return nil, nil
 				},
 			},
-			"inodesused": &graphql.Field{
+			"timezone": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Used inodes",
-				Args: graphql.FieldConfigArgument{
-					"mode": &graphql.ArgumentConfig{
-						Type:        modeEnum,
-						Description: "Either in BYTES or PERCENT",
-						DefaultValue: false,
-					},
+				Description: "System timezone",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if host, ok := p.Source.(types.HostInfo); ok {
+						return host.Timezone, nil
+					}
+					return nil, nil
 				},
+			},
+			"timezoneOffsetSec": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Timezone offset (seconds from UTC)",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if disk, ok := p.Source.(Disk); ok {
-						if p.Args["mode"].(bool) {
-							return disk.UsageStat.InodesUsedPercent, nil
-						}
-						return disk.UsageStat.InodesUsed, nil
+					if host, ok := p.Source.(types.HostInfo); ok {
+						return host.TimezoneOffsetSec, nil
 					}
 					return nil, nil
 				},
 			},
-			"inodesfree": &graphql.Field{
+			"uniqueID": &graphql.Field{
 				Type:        graphql.NewNonNull(graphql.String),
-				Description: "Free inodes",
+				Description: "Unique ID of the host (optional)",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if host, ok := p.Source.(types.HostInfo); ok {
+						return host.UniqueID, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	swapType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Swap",
+		Description: "Swap space",
+		Fields: graphql.Fields{
+			"total": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Total swap space",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if swap, ok := p.Source.(Swap); ok {
+						return swap.Total, nil
+					}
+					return nil, nil
+				},
+			}, swapTotal),
+			"used": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Used swap space",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if swap, ok := p.Source.(Swap); ok {
+						return swap.Used, nil
+					}
+					return nil, nil
+				},
+			}, swapTotal),
+			"free": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Free swap space",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if swap, ok := p.Source.(Swap); ok {
+						return swap.Free, nil
+					}
+					return nil, nil
+				},
+			}, swapTotal),
+			"cached": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Memory that was swapped out and back in but is still in the swap",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if swap, ok := p.Source.(Swap); ok {
+						return swap.Cached, nil
+					}
+					return nil, nil
+				},
+			}, swapTotal),
+			"swapIn": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Pages swapped in since boot",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if swap, ok := p.Source.(Swap); ok {
+						return swap.In, nil
+					}
+					return nil, nil
+				},
+			},
+			"swapOut": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Pages swapped out since boot",
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if disk, ok := p.Source.(Disk); ok {
-						return disk.UsageStat.InodesFree, nil
+					if swap, ok := p.Source.(Swap); ok {
+						return swap.Out, nil
 					}
 					return nil, nil
 				},
 			},
 		},
 	})
+
+	hugepagesType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Hugepages",
+		Description: "Huge pages of the default size",
+		Fields: graphql.Fields{
+			"total": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Pages in the pool",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if huge, ok := p.Source.(Hugepages); ok {
+						return huge.Total, nil
+					}
+					return nil, nil
+				},
+			},
+			"free": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Pages not allocated yet",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if huge, ok := p.Source.(Hugepages); ok {
+						return huge.Free, nil
+					}
+					return nil, nil
+				},
+			},
+			"reserved": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Pages committed to but not allocated yet",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if huge, ok := p.Source.(Hugepages); ok {
+						return huge.Reserved, nil
+					}
+					return nil, nil
+				},
+			},
+			"surplus": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Pages allocated above the size of the pool",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if huge, ok := p.Source.(Hugepages); ok {
+						return huge.Surplus, nil
+					}
+					return nil, nil
+				},
+			},
+			"pageSize": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Size of a huge page",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if huge, ok := p.Source.(Hugepages); ok {
+						return huge.PageSize, nil
+					}
+					return nil, nil
+				},
+			}, nil),
+			"hugetlb": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Memory used by huge pages of all sizes",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if huge, ok := p.Source.(Hugepages); ok {
+						return huge.Hugetlb, nil
+					}
+					return nil, nil
+				},
+			}, nil),
+		},
+	})
+
+	statType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Stat",
+		Description: "Named value read from a file like /proc/meminfo or /proc/vmstat",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Name in the file",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if stat, ok := p.Source.(Stat); ok {
+						return stat.Name, nil
+					}
+					return nil, nil
+				},
+			},
+			"value": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Value, in bytes for the sizes of /proc/meminfo",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if stat, ok := p.Source.(Stat); ok {
+						return stat.Value, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	memoryType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Memory",
+		Description: "Host memory info",
+		Fields: graphql.Fields{
+			"total": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Total physical memory in bytes",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						return mem.Total, nil
+					}
+					return nil, nil
+				},
+			}, memoryTotal),
+			"used": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Total used memory in bytes",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						return mem.Used, nil
+					}
+					return nil, nil
+				},
+			}, memoryTotal),
+			"available": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Amount of memory available without swapping in bytes",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						return mem.Available, nil
+					}
+					return nil, nil
+				},
+			}, memoryTotal),
+			"free": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Amount of memory not used by the system in bytes",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						return mem.Free, nil
+					}
+					return nil, nil
+				},
+			}, memoryTotal),
+			"virtualTotal": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Total virtual memory in bytes",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						return mem.VirtualTotal, nil
+					}
+					return nil, nil
+				},
+			}, virtualMemoryTotal),
+			"virtualUsed": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Total used virtual memory in bytes",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						return mem.VirtualUsed, nil
+					}
+					return nil, nil
+				},
+			}, virtualMemoryTotal),
+			"virtualFree": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Virtual memory that is not used in bytes",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						return mem.VirtualFree, nil
+					}
+					return nil, nil
+				},
+			}, virtualMemoryTotal),
+			"cached": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Page cache",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						return mem.Metrics["Cached"], nil
+					}
+					return nil, nil
+				},
+			}, memoryTotal),
+			"buffers": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Block device buffers",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						return mem.Metrics["Buffers"], nil
+					}
+					return nil, nil
+				},
+			}, memoryTotal),
+			"dirty": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Memory waiting to be written back to disk",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						return mem.Metrics["Dirty"], nil
+					}
+					return nil, nil
+				},
+			}, memoryTotal),
+			"writeback": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Memory being written back to disk",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						return mem.Metrics["Writeback"], nil
+					}
+					return nil, nil
+				},
+			}, memoryTotal),
+			"slab": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Kernel slab caches",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						return mem.Metrics["Slab"], nil
+					}
+					return nil, nil
+				},
+			}, memoryTotal),
+			"shmem": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Shared memory and tmpfs",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						return mem.Metrics["Shmem"], nil
+					}
+					return nil, nil
+				},
+			}, memoryTotal),
+			"swap": &graphql.Field{
+				Type:        graphql.NewNonNull(swapType),
+				Description: "Swap space",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						return mem.swap(), nil
+					}
+					return nil, nil
+				},
+			},
+			"hugepages": &graphql.Field{
+				Type:        graphql.NewNonNull(hugepagesType),
+				Description: "Huge pages of the default size",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						return mem.hugepages(), nil
+					}
+					return nil, nil
+				},
+			},
+			"meminfo": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(statType)),
+				Description: "Values of /proc/meminfo",
+				Args: graphql.FieldConfigArgument{
+					"key": &graphql.ArgumentConfig{
+						Description: "Name of the value (e.g. Mapped), all of them are listed when omitted",
+						Type:        graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						key, _ := p.Args["key"].(string)
+						return statList(mem.meminfo(), key), nil
+					}
+					return nil, nil
+				},
+			},
+			"vmstat": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(statType)),
+				Description: "Counters of /proc/vmstat",
+				Args: graphql.FieldConfigArgument{
+					"key": &graphql.ArgumentConfig{
+						Description: "Name of the counter (e.g. pgmajfault, oom_kill), all of them are listed when omitted",
+						Type:        graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if mem, ok := p.Source.(Memory); ok {
+						key, _ := p.Args["key"].(string)
+						return statList(mem.VMStat, key), nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	interfaceRateType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "InterfaceRate",
+		Description: "Network interface traffic",
+		Fields: graphql.Fields{
+			"rxBytes": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Bytes received per second",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(InterfaceRate); ok {
+						return rate.RxBytes, nil
+					}
+					return nil, nil
+				},
+			}, nil),
+			"txBytes": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Bytes sent per second",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(InterfaceRate); ok {
+						return rate.TxBytes, nil
+					}
+					return nil, nil
+				},
+			}, nil),
+			"rxPackets": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Packets received per second",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(InterfaceRate); ok {
+						return rate.RxPackets, nil
+					}
+					return nil, nil
+				},
+			},
+			"txPackets": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Packets sent per second",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(InterfaceRate); ok {
+						return rate.TxPackets, nil
+					}
+					return nil, nil
+				},
+			},
+			"rxErrors": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Receive errors per second",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(InterfaceRate); ok {
+						return rate.RxErrors, nil
+					}
+					return nil, nil
+				},
+			},
+			"txErrors": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Transmit errors per second",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(InterfaceRate); ok {
+						return rate.TxErrors, nil
+					}
+					return nil, nil
+				},
+			},
+			"rxDrops": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Incoming packets dropped per second",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(InterfaceRate); ok {
+						return rate.RxDrops, nil
+					}
+					return nil, nil
+				},
+			},
+			"txDrops": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Outgoing packets dropped per second",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(InterfaceRate); ok {
+						return rate.TxDrops, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	interfaceType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Interface",
+		Description: "Network interface",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Interface name",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						return iface.Name, nil
+					}
+					return nil, nil
+				},
+			},
+			"mtu": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Maximum transmission unit",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						return iface.MTU, nil
+					}
+					return nil, nil
+				},
+			},
+			"hardwareAddr": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "MAC address",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						return iface.HardwareAddr, nil
+					}
+					return nil, nil
+				},
+			},
+			"flags": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.String)),
+				Description: "Interface flags (e.g. up, broadcast, loopback)",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						return iface.Flags, nil
+					}
+					return nil, nil
+				},
+			},
+			"addresses": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.String)),
+				Description: "IP addresses in CIDR notation",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						return iface.Addrs, nil
+					}
+					return nil, nil
+				},
+			},
+			"speed": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Link speed in Mbps, null when unknown",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						return iface.Speed, nil
+					}
+					return nil, nil
+				},
+			},
+			"operState": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Operational state (e.g. up, down, unknown)",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						return iface.OperState, nil
+					}
+					return nil, nil
+				},
+			},
+			"rxBytes": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Bytes received",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						return iface.Counters.BytesRecv, nil
+					}
+					return nil, nil
+				},
+			}, nil),
+			"txBytes": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Bytes sent",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						return iface.Counters.BytesSent, nil
+					}
+					return nil, nil
+				},
+			}, nil),
+			"rxPackets": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Packets received",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						return iface.Counters.PacketsRecv, nil
+					}
+					return nil, nil
+				},
+			},
+			"txPackets": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Packets sent",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						return iface.Counters.PacketsSent, nil
+					}
+					return nil, nil
+				},
+			},
+			"rxErrors": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Receive errors",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						return iface.Counters.Errin, nil
+					}
+					return nil, nil
+				},
+			},
+			"txErrors": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Transmit errors",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						return iface.Counters.Errout, nil
+					}
+					return nil, nil
+				},
+			},
+			"rxDrops": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Incoming packets dropped",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						return iface.Counters.Dropin, nil
+					}
+					return nil, nil
+				},
+			},
+			"txDrops": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Outgoing packets dropped",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						return iface.Counters.Dropout, nil
+					}
+					return nil, nil
+				},
+			},
+			"rate": &graphql.Field{
+				Type:        interfaceRateType,
+				Description: "Traffic computed between two background samples",
+				Args: graphql.FieldConfigArgument{
+					"window": &graphql.ArgumentConfig{
+						Description: "Time between the two samples (e.g. 1m), defaults to the last two",
+						Type:        graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if iface, ok := p.Source.(Interface); ok {
+						window, err := parseWindow(p.Args["window"])
+						if err != nil {
+							return nil, err
+						}
+						rate, err := interfaceRate(iface.Name, window)
+						if err != nil {
+							return nil, err
+						}
+						return rate, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	connectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Connection",
+		Description: "Network socket",
+		Fields: graphql.Fields{
+			"protocol": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Protocol (tcp, tcp6, udp, udp6 or unix)",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if conn, ok := p.Source.(Connection); ok {
+						return conn.Protocol, nil
+					}
+					return nil, nil
+				},
+			},
+			"localAddress": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Local IP address, or path of Unix sockets",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if conn, ok := p.Source.(Connection); ok {
+						return conn.LocalAddress, nil
+					}
+					return nil, nil
+				},
+			},
+			"localPort": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Local port",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if conn, ok := p.Source.(Connection); ok {
+						return conn.LocalPort, nil
+					}
+					return nil, nil
+				},
+			},
+			"remoteAddress": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Remote IP address",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if conn, ok := p.Source.(Connection); ok {
+						return conn.RemoteAddress, nil
+					}
+					return nil, nil
+				},
+			},
+			"remotePort": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Remote port",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if conn, ok := p.Source.(Connection); ok {
+						return conn.RemotePort, nil
+					}
+					return nil, nil
+				},
+			},
+			"state": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Socket state (e.g. LISTEN, ESTABLISHED, TIME_WAIT)",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if conn, ok := p.Source.(Connection); ok {
+						return conn.State, nil
+					}
+					return nil, nil
+				},
+			},
+			"inode": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Socket inode",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if conn, ok := p.Source.(Connection); ok {
+						return conn.Inode, nil
+					}
+					return nil, nil
+				},
+			},
+			"uid": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "User owning the socket, null for Unix sockets",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if conn, ok := p.Source.(Connection); ok && conn.UID >= 0 {
+						return conn.UID, nil
+					}
+					return nil, nil
+				},
+			},
+			"pid": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Process owning the socket, null when unknown",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if conn, ok := p.Source.(Connection); ok && conn.PID != 0 {
+						return conn.PID, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	networkType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Network",
+		Description: "Host network info",
+		Fields: graphql.Fields{
+			"netstat": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(counterType)),
+				Description: "Netstat counters",
+				Args: graphql.FieldConfigArgument{
+					"protocol": &graphql.ArgumentConfig{
+						Description: "Either IP or TCP, all of them are listed when omitted",
+						Type:        protocolNetstatEnum,
+					},
+					"counter": &graphql.ArgumentConfig{
+						Description: "Counter name, all of them are listed when omitted",
+						Type:        graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if net, ok := p.Source.(Network); ok {
+						protocols := []string{"IPExt", "TCPExt"}
+						if protocol, ok := p.Args["protocol"].(string); ok {
+							protocols = []string{protocol + "Ext"}
+						}
+						name, _ := p.Args["counter"].(string)
+						return net.counterList(protocols, name), nil
+					}
+					return nil, nil
+				},
+			},
+			"snmp": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(counterType)),
+				Description: "SNMP counters",
+				Args: graphql.FieldConfigArgument{
+					"protocol": &graphql.ArgumentConfig{
+						Description: "Either IP, ICMP, ICMPMsg, TCP, UDP or UDPLite, all of them are listed when omitted",
+						Type:        protocolSNMPEnum,
+					},
+					"counter": &graphql.ArgumentConfig{
+						Description: "Counter name, all of them are listed when omitted",
+						Type:        graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if net, ok := p.Source.(Network); ok {
+						protocols := []string{"IP", "ICMP", "ICMPMsg", "TCP", "UDP", "UDPLite"}
+						if protocol, ok := p.Args["protocol"].(string); ok {
+							protocols = []string{protocol}
+						}
+						name, _ := p.Args["counter"].(string)
+						return net.counterList(protocols, name), nil
+					}
+					return nil, nil
+				},
+			},
+			"connections": &graphql.Field{
+				Type:        graphql.NewList(connectionType),
+				Description: "Sockets, like ss or netstat list them",
+				Args: graphql.FieldConfigArgument{
+					"protocol": &graphql.ArgumentConfig{
+						Description: "Either TCP, TCP4, TCP6, UDP, UDP4, UDP6 or UNIX",
+						Type:        connectionProtocolEnum,
+					},
+					"state": &graphql.ArgumentConfig{
+						Description: "Socket state (e.g. LISTEN, ESTABLISHED)",
+						Type:        graphql.String,
+					},
+					"localPort": &graphql.ArgumentConfig{
+						Description: "Local port",
+						Type:        graphql.Int,
+					},
+					"pid": &graphql.ArgumentConfig{
+						Description: "Owning process ID",
+						Type:        graphql.Int,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var filter ConnectionFilter
+					filter.Protocol, _ = p.Args["protocol"].(string)
+					filter.State, _ = p.Args["state"].(string)
+					filter.LocalPort, _ = p.Args["localPort"].(int)
+					filter.PID, _ = p.Args["pid"].(int)
+					connections, err := Collectors.Collect(p.Context, "connections")
+					if err != nil {
+						return nil, err
+					}
+					return selectConnections(connections.([]Connection), filter), nil
+				},
+			},
+			"listeners": &graphql.Field{
+				Type:        graphql.NewList(connectionType),
+				Description: "Listening TCP sockets and bound UDP sockets, sorted by port",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					connections, err := Collectors.Collect(p.Context, "connections")
+					if err != nil {
+						return nil, err
+					}
+					return selectListeners(connections.([]Connection)), nil
+				},
+			},
+			"interfaces": &graphql.Field{
+				Type:        graphql.NewList(interfaceType),
+				Description: "Network interfaces and their counters",
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{
+						Description: "Name of the interface",
+						Type:        graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					interfaces, err := Collectors.Collect(p.Context, "interfaces")
+					if err != nil {
+						return nil, err
+					}
+					name, _ := p.Args["name"].(string)
+					selected, err := selectInterfaces(interfaces.([]Interface), name)
+					if err != nil {
+						return nil, collectorError("interfaces", err)
+					}
+					return selected, nil
+				},
+			},
+			"rate": &graphql.Field{
+				Type:        graphql.Float,
+				Description: "Per second rate of a counter computed between two background samples",
+				Args: graphql.FieldConfigArgument{
+					"protocol": &graphql.ArgumentConfig{
+						Description: "Either IP, ICMP, ICMPMsg, TCP, UDP, UDPLite, IPExt or TCPExt",
+						Type:        graphql.NewNonNull(rateProtocolEnum),
+					},
+					"counter": &graphql.ArgumentConfig{
+						Description: "SNMP or netstat counter",
+						Type:        graphql.NewNonNull(graphql.String),
+					},
+					"window": &graphql.ArgumentConfig{
+						Description: "Time between the two samples (e.g. 1m), defaults to the last two",
+						Type:        graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					window, err := parseWindow(p.Args["window"])
+					if err != nil {
+						return nil, err
+					}
+					rate, err := networkRate(p.Args["protocol"].(string), p.Args["counter"].(string), window)
+					if err != nil {
+						return nil, err
+					}
+					return rate, nil
+				},
+			},
+		},
+	})
+
+	osType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "OS",
+		Description: "Host OS info",
+		Fields: graphql.Fields{
+			"type": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "OS Type (one of linux, macos, unix, windows)",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if os, ok := p.Source.(types.OSInfo); ok {
+						return os.Type, nil
+					}
+					return nil, nil
+				},
+			},
+			"family": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "OS Family (e.g. redhat, debian, freebsd, windows)",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if os, ok := p.Source.(types.OSInfo); ok {
+						return os.Family, nil
+					}
+					return nil, nil
+				},
+			},
+			"platform": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "OS platform (e.g. centos, ubuntu, windows)",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if os, ok := p.Source.(types.OSInfo); ok {
+						return os.Platform, nil
+					}
+					return nil, nil
+				},
+			},
+			"name": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "OS Name (e.g. Mac OS X, CentOS)",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if os, ok := p.Source.(types.OSInfo); ok {
+						return os.Name, nil
+					}
+					return nil, nil
+				},
+			},
+			"version": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "OS version (e.g. 10.12.6)",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if os, ok := p.Source.(types.OSInfo); ok {
+						return os.Version, nil
+					}
+					return nil, nil
+				},
+			},
+			"major": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Major release version",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if os, ok := p.Source.(types.OSInfo); ok {
+						return os.Major, nil
+					}
+					return nil, nil
+				},
+			},
+			"minor": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Minor release version",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if os, ok := p.Source.(types.OSInfo); ok {
+						return os.Minor, nil
+					}
+					return nil, nil
+				},
+			},
+			"patch": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Patch release version",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if os, ok := p.Source.(types.OSInfo); ok {
+						return os.Patch, nil
+					}
+					return nil, nil
+				},
+			},
+			"build": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Build (e.g. 16G1114)",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if os, ok := p.Source.(types.OSInfo); ok {
+						return os.Build, nil
+					}
+					return nil, nil
+				},
+			},
+			"codename": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "OS codename (e.g. jessie)",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if os, ok := p.Source.(types.OSInfo); ok {
+						return os.Codename, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	diskIORateType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "DiskIORate",
+		Description: "Disk throughput and latency",
+		Fields: graphql.Fields{
+			"readBytes": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Bytes read per second",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(DiskIORate); ok {
+						return rate.ReadBytes, nil
+					}
+					return nil, nil
+				},
+			}, nil),
+			"writeBytes": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Bytes written per second",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(DiskIORate); ok {
+						return rate.WriteBytes, nil
+					}
+					return nil, nil
+				},
+			}, nil),
+			"readOps": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Reads completed per second",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(DiskIORate); ok {
+						return rate.ReadOps, nil
+					}
+					return nil, nil
+				},
+			},
+			"writeOps": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Writes completed per second",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(DiskIORate); ok {
+						return rate.WriteOps, nil
+					}
+					return nil, nil
+				},
+			},
+			"readAwait": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Average time spent on a read in milliseconds",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(DiskIORate); ok {
+						return rate.ReadAwait, nil
+					}
+					return nil, nil
+				},
+			},
+			"writeAwait": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Average time spent on a write in milliseconds",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(DiskIORate); ok {
+						return rate.WriteAwait, nil
+					}
+					return nil, nil
+				},
+			},
+			"await": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Average time spent on a request in milliseconds",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(DiskIORate); ok {
+						return rate.Await, nil
+					}
+					return nil, nil
+				},
+			},
+			"utilization": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Percentage of time the device was busy",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if rate, ok := p.Source.(DiskIORate); ok {
+						return rate.Utilization, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	diskIOType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "DiskIO",
+		Description: "Disk IO counters",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Device name",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(disk.IOCountersStat); ok {
+						return io.Name, nil
+					}
+					return nil, nil
+				},
+			},
+			"readBytes": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Bytes read",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(disk.IOCountersStat); ok {
+						return io.ReadBytes, nil
+					}
+					return nil, nil
+				},
+			}, nil),
+			"writeBytes": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Bytes written",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(disk.IOCountersStat); ok {
+						return io.WriteBytes, nil
+					}
+					return nil, nil
+				},
+			}, nil),
+			"readCount": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Reads completed",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(disk.IOCountersStat); ok {
+						return io.ReadCount, nil
+					}
+					return nil, nil
+				},
+			},
+			"writeCount": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Writes completed",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(disk.IOCountersStat); ok {
+						return io.WriteCount, nil
+					}
+					return nil, nil
+				},
+			},
+			"mergedReadCount": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Adjacent reads merged together",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(disk.IOCountersStat); ok {
+						return io.MergedReadCount, nil
+					}
+					return nil, nil
+				},
+			},
+			"mergedWriteCount": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Adjacent writes merged together",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(disk.IOCountersStat); ok {
+						return io.MergedWriteCount, nil
+					}
+					return nil, nil
+				},
+			},
+			"readTime": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Time spent reading",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(disk.IOCountersStat); ok {
+						return time.Duration(io.ReadTime) * time.Millisecond, nil
+					}
+					return nil, nil
+				},
+			}),
+			"writeTime": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Time spent writing",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(disk.IOCountersStat); ok {
+						return time.Duration(io.WriteTime) * time.Millisecond, nil
+					}
+					return nil, nil
+				},
+			}),
+			"ioTime": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Time spent doing IO",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(disk.IOCountersStat); ok {
+						return time.Duration(io.IoTime) * time.Millisecond, nil
+					}
+					return nil, nil
+				},
+			}),
+			"weightedIO": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Time spent doing IO weighted by the number of requests in flight",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(disk.IOCountersStat); ok {
+						return time.Duration(io.WeightedIO) * time.Millisecond, nil
+					}
+					return nil, nil
+				},
+			}),
+			"iopsInProgress": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Requests currently in flight",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(disk.IOCountersStat); ok {
+						return io.IopsInProgress, nil
+					}
+					return nil, nil
+				},
+			},
+			"rate": &graphql.Field{
+				Type:        diskIORateType,
+				Description: "Throughput and latency computed between two background samples",
+				Args: graphql.FieldConfigArgument{
+					"window": &graphql.ArgumentConfig{
+						Description: "Time between the two samples (e.g. 1m), defaults to the last two",
+						Type:        graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(disk.IOCountersStat); ok {
+						window, err := parseWindow(p.Args["window"])
+						if err != nil {
+							return nil, err
+						}
+						rate, err := diskIORate(io.Name, window)
+						if err != nil {
+							return nil, err
+						}
+						return rate, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	partitionType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Partition",
+		Description: "Mounted partition and its usage",
+		Fields: partitionFields(func(t graphql.Output) graphql.Output {
+			return graphql.NewNonNull(t)
+		}),
+	})
+
+	diskFields := partitionFields(func(t graphql.Output) graphql.Output {
+		return t
+	})
+	diskFields["devices"] = &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.String)),
+		Description: "List of devices",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if disk, ok := p.Source.(Disk); ok {
+				var devices []string
+				for _, device := range disk.Partitions {
+					devices = append(devices, device.Device)
+				}
+				return devices, nil
+			}
+			return nil, nil
+		},
+	}
+	diskFields["partitions"] = &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(partitionType)),
+		Description: "Mounted partitions and their usage",
+		Args: graphql.FieldConfigArgument{
+			"fstype": &graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "Only partitions with this filesystem type",
+			},
+			"mountpointPrefix": &graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "Only partitions mounted under this path",
+			},
+			"includeVirtual": &graphql.ArgumentConfig{
+				Type:         graphql.Boolean,
+				Description:  "Include virtual filesystems such as proc, sysfs or tmpfs",
+				DefaultValue: false,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			table, err := Collectors.Collect(p.Context, "partitions")
+			if err != nil {
+				return nil, err
+			}
+			fstype, _ := p.Args["fstype"].(string)
+			mountpointPrefix, _ := p.Args["mountpointPrefix"].(string)
+			partitions := table.(*partitionTable).selectPartitions(fstype, mountpointPrefix, p.Args["includeVirtual"].(bool))
+			partitions, err = table.(*partitionTable).withUsage(p.Context, partitions)
+			if err != nil {
+				return nil, collectorError("partitions", err)
+			}
+			return partitions, nil
+		},
+	}
+	diskFields["io"] = &graphql.Field{
+		Type:        graphql.NewList(diskIOType),
+		Description: "IO counters of each device",
+		Args: graphql.FieldConfigArgument{
+			"device": &graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "Name of the device, defaults to the one the disk was queried with",
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if diskObj, ok := p.Source.(Disk); ok {
+				device, _ := p.Args["device"].(string)
+				if device == "" {
+					device = diskObj.Device
+				}
+				io, err := Collectors.Collect(p.Context, "diskio")
+				if err != nil {
+					return nil, err
+				}
+				return selectDiskIO(io.([]disk.IOCountersStat), device), nil
+			}
+			return nil, nil
+		},
+	}
+
+	diskType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Disk",
+		Description: "Disk info",
+		Fields:      diskFields,
+	})
+
+	processType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Process",
+		Description: "Process info",
+		Fields: graphql.Fields{
+			"pid": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Process ID",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if proc, ok := p.Source.(Process); ok {
+						return proc.PID, nil
+					}
+					return nil, nil
+				},
+			},
+			"ppid": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Parent process ID",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if proc, ok := p.Source.(Process); ok {
+						return proc.PPID, nil
+					}
+					return nil, nil
+				},
+			},
+			"name": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Process name",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if proc, ok := p.Source.(Process); ok {
+						return proc.Name, nil
+					}
+					return nil, nil
+				},
+			},
+			"exe": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Path to the process executable",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if proc, ok := p.Source.(Process); ok {
+						return proc.Exe, nil
+					}
+					return nil, nil
+				},
+			},
+			"cmdline": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.String)),
+				Description: "Command line arguments",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if proc, ok := p.Source.(Process); ok {
+						return proc.Cmdline, nil
+					}
+					return nil, nil
+				},
+			},
+			"user": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Owner of the process",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if proc, ok := p.Source.(Process); ok {
+						return proc.User, nil
+					}
+					return nil, nil
+				},
+			},
+			"state": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Process state (e.g. R running, S sleeping, D disk sleep, Z zombie)",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if proc, ok := p.Source.(Process); ok {
+						return proc.State, nil
+					}
+					return nil, nil
+				},
+			},
+			"rss": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Resident set size",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if proc, ok := p.Source.(Process); ok {
+						return proc.RSS, nil
+					}
+					return nil, nil
+				},
+			}, hostMemoryTotal),
+			"vms": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Virtual memory size",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if proc, ok := p.Source.(Process); ok {
+						return proc.VMS, nil
+					}
+					return nil, nil
+				},
+			}, hostMemoryTotal),
+			"cpuPercent": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "CPU usage percentage over the lifetime of the process",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if proc, ok := p.Source.(Process); ok {
+						return proc.CPUUsage, nil
+					}
+					return nil, nil
+				},
+			},
+			"threads": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Number of threads",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if proc, ok := p.Source.(Process); ok {
+						return proc.NumThreads, nil
+					}
+					return nil, nil
+				},
+			},
+			"startTime": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.DateTime),
+				Description: "Process start time",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if proc, ok := p.Source.(Process); ok {
+						return proc.StartTime, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	pressureStatType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "PressureStat",
+		Description: "Share of time tasks were stalled waiting for a resource",
+		Fields: graphql.Fields{
+			"avg10": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Percentage of time stalled over the last 10 seconds",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if stat, ok := p.Source.(PressureStat); ok {
+						return stat.Avg10, nil
+					}
+					return nil, nil
+				},
+			},
+			"avg60": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Percentage of time stalled over the last minute",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if stat, ok := p.Source.(PressureStat); ok {
+						return stat.Avg60, nil
+					}
+					return nil, nil
+				},
+			},
+			"avg300": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Percentage of time stalled over the last 5 minutes",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if stat, ok := p.Source.(PressureStat); ok {
+						return stat.Avg300, nil
+					}
+					return nil, nil
+				},
+			},
+			"total": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Time stalled since boot",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if stat, ok := p.Source.(PressureStat); ok {
+						return stat.Total, nil
+					}
+					return nil, nil
+				},
+			}),
+		},
+	})
+
+	pressureResourceType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "PressureResource",
+		Description: "Pressure stall information of a resource",
+		Fields: graphql.Fields{
+			"some": &graphql.Field{
+				Type:        graphql.NewNonNull(pressureStatType),
+				Description: "Time at least one task was stalled",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if resource, ok := p.Source.(PressureResource); ok {
+						return resource.Some, nil
+					}
+					return nil, nil
+				},
+			},
+			"full": &graphql.Field{
+				Type:        pressureStatType,
+				Description: "Time all non-idle tasks were stalled at once, null for the CPU of the host on kernels before 5.13",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if resource, ok := p.Source.(PressureResource); ok && resource.Full != nil {
+						return *resource.Full, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	pressureType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Pressure",
+		Description: "Pressure stall information",
+		Fields: graphql.Fields{
+			"cpu": &graphql.Field{
+				Type:        pressureResourceType,
+				Description: "Pressure on the CPU, null when not available",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if pressure, ok := p.Source.(Pressure); ok {
+						resource, err := pressure.resource("cpu")
+						if err != nil {
+							return nil, collectorError("pressure", err)
+						}
+						return resource, nil
+					}
+					return nil, nil
+				},
+			},
+			"memory": &graphql.Field{
+				Type:        pressureResourceType,
+				Description: "Pressure on memory, null when not available",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if pressure, ok := p.Source.(Pressure); ok {
+						resource, err := pressure.resource("memory")
+						if err != nil {
+							return nil, collectorError("pressure", err)
+						}
+						return resource, nil
+					}
+					return nil, nil
+				},
+			},
+			"io": &graphql.Field{
+				Type:        pressureResourceType,
+				Description: "Pressure on IO, null when not available",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if pressure, ok := p.Source.(Pressure); ok {
+						resource, err := pressure.resource("io")
+						if err != nil {
+							return nil, collectorError("pressure", err)
+						}
+						return resource, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	cgroupCPUType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "CgroupCPU",
+		Description: "CPU usage and throttling of a cgroup",
+		Fields: graphql.Fields{
+			"usage": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "CPU time used",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cpu, ok := p.Source.(CgroupCPU); ok {
+						return cpu.Usage, nil
+					}
+					return nil, nil
+				},
+			}),
+			"user": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "CPU time used in user mode",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cpu, ok := p.Source.(CgroupCPU); ok {
+						return cpu.User, nil
+					}
+					return nil, nil
+				},
+			}),
+			"system": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "CPU time used in kernel mode",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cpu, ok := p.Source.(CgroupCPU); ok {
+						return cpu.System, nil
+					}
+					return nil, nil
+				},
+			}),
+			"periods": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Enforcement periods elapsed",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cpu, ok := p.Source.(CgroupCPU); ok {
+						return cpu.Periods, nil
+					}
+					return nil, nil
+				},
+			},
+			"throttledPeriods": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Periods the cgroup was throttled in",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cpu, ok := p.Source.(CgroupCPU); ok {
+						return cpu.ThrottledPeriods, nil
+					}
+					return nil, nil
+				},
+			},
+			"throttledTime": durationField(&graphql.Field{
+				Type:        graphql.NewNonNull(DurationScalar),
+				Description: "Time the cgroup was throttled for",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cpu, ok := p.Source.(CgroupCPU); ok {
+						return cpu.ThrottledTime, nil
+					}
+					return nil, nil
+				},
+			}),
+			"quota": durationField(&graphql.Field{
+				Type:        DurationScalar,
+				Description: "CPU time allowed per period, null when unlimited",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cpu, ok := p.Source.(CgroupCPU); ok && cpu.Quota != nil {
+						return *cpu.Quota, nil
+					}
+					return nil, nil
+				},
+			}),
+			"period": durationField(&graphql.Field{
+				Type:        DurationScalar,
+				Description: "Length of an enforcement period, null without the cpu controller",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cpu, ok := p.Source.(CgroupCPU); ok && cpu.Period != nil {
+						return *cpu.Period, nil
+					}
+					return nil, nil
+				},
+			}),
+		},
+	})
+
+	cgroupMemoryType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "CgroupMemory",
+		Description: "Memory usage and limit of a cgroup",
+		Fields: graphql.Fields{
+			"current": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Memory used by the cgroup and its descendants",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if memory, ok := p.Source.(CgroupMemory); ok {
+						return memory.Current, nil
+					}
+					return nil, nil
+				},
+			}, cgroupMemoryLimit),
+			"max": bytesField(&graphql.Field{
+				Type:        BytesScalar,
+				Description: "Memory limit, null when unlimited",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if memory, ok := p.Source.(CgroupMemory); ok && memory.Max != nil {
+						return *memory.Max, nil
+					}
+					return nil, nil
+				},
+			}, nil),
+			"low": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Times the usage went under the low boundary while reclaiming memory",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if memory, ok := p.Source.(CgroupMemory); ok {
+						return memory.Events["low"], nil
+					}
+					return nil, nil
+				},
+			},
+			"high": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Times the cgroup was throttled for going over its high boundary",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if memory, ok := p.Source.(CgroupMemory); ok {
+						return memory.Events["high"], nil
+					}
+					return nil, nil
+				},
+			},
+			"maxEvents": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Times the usage was about to go over the limit",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if memory, ok := p.Source.(CgroupMemory); ok {
+						return memory.Events["max"], nil
+					}
+					return nil, nil
+				},
+			},
+			"oom": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Times the usage reached the limit and allocations failed",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if memory, ok := p.Source.(CgroupMemory); ok {
+						return memory.Events["oom"], nil
+					}
+					return nil, nil
+				},
+			},
+			"oomKill": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Processes killed by the OOM killer",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if memory, ok := p.Source.(CgroupMemory); ok {
+						return memory.Events["oom_kill"], nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	cgroupIOType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "CgroupIO",
+		Description: "IO of a cgroup on a block device",
+		Fields: graphql.Fields{
+			"device": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Device name",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(CgroupIO); ok {
+						return io.Device, nil
+					}
+					return nil, nil
+				},
+			},
+			"readBytes": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Bytes read",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(CgroupIO); ok {
+						return io.ReadBytes, nil
+					}
+					return nil, nil
+				},
+			}, nil),
+			"writeBytes": bytesField(&graphql.Field{
+				Type:        graphql.NewNonNull(BytesScalar),
+				Description: "Bytes written",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(CgroupIO); ok {
+						return io.WriteBytes, nil
+					}
+					return nil, nil
+				},
+			}, nil),
+			"reads": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Read operations",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(CgroupIO); ok {
+						return io.Reads, nil
+					}
+					return nil, nil
+				},
+			},
+			"writes": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Write operations",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if io, ok := p.Source.(CgroupIO); ok {
+						return io.Writes, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	cgroupPidsType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "CgroupPids",
+		Description: "Number of tasks in a cgroup",
+		Fields: graphql.Fields{
+			"current": &graphql.Field{
+				Type:        graphql.NewNonNull(UInt64Scalar),
+				Description: "Tasks in the cgroup and its descendants",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if pids, ok := p.Source.(CgroupPids); ok {
+						return pids.Current, nil
+					}
+					return nil, nil
+				},
+			},
+			"max": &graphql.Field{
+				Type:        UInt64Scalar,
+				Description: "Task limit, null when unlimited",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if pids, ok := p.Source.(CgroupPids); ok && pids.Max != nil {
+						return *pids.Max, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	cgroupType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Cgroup",
+		Description: "Resource usage of a cgroup v2",
+		Fields: graphql.Fields{
+			"path": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Path in the cgroup hierarchy",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cgroup, ok := p.Source.(Cgroup); ok {
+						return cgroup.Path, nil
+					}
+					return nil, nil
+				},
+			},
+			"cpu": &graphql.Field{
+				Type:        cgroupCPUType,
+				Description: "CPU usage, null when not available",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cgroup, ok := p.Source.(Cgroup); ok && cgroup.CPU != nil {
+						return *cgroup.CPU, nil
+					}
+					return nil, nil
+				},
+			},
+			"memory": &graphql.Field{
+				Type:        cgroupMemoryType,
+				Description: "Memory usage, null without the memory controller",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cgroup, ok := p.Source.(Cgroup); ok && cgroup.Memory != nil {
+						return *cgroup.Memory, nil
+					}
+					return nil, nil
+				},
+			},
+			"io": &graphql.Field{
+				Type:        graphql.NewList(cgroupIOType),
+				Description: "IO per device, null without the io controller",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cgroup, ok := p.Source.(Cgroup); ok && cgroup.IO != nil {
+						return cgroup.IO, nil
+					}
+					return nil, nil
+				},
+			},
+			"pids": &graphql.Field{
+				Type:        cgroupPidsType,
+				Description: "Number of tasks, null without the pids controller",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cgroup, ok := p.Source.(Cgroup); ok && cgroup.Pids != nil {
+						return *cgroup.Pids, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	// Added once the type exists, to refer to itself
+	cgroupType.AddFieldConfig("children", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(cgroupType)),
+		Description: "Cgroups below this one, only listed when recursive",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if cgroup, ok := p.Source.(Cgroup); ok {
+				return cgroup.Children, nil
+			}
+			return nil, nil
+		},
+	})
+
+	// Added once the process type exists
+	connectionType.AddFieldConfig("process", &graphql.Field{
+		Type:        processType,
+		Description: "Process owning the socket, null when unknown or gone",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if conn, ok := p.Source.(Connection); ok && conn.PID != 0 {
+				proc, err := getProcess(conn.PID)
+				if err == nil {
+					var procObj Process
+					if procObj, err = newProcess(proc); err == nil {
+						return procObj, nil
+					}
+				}
+				// The process exited since the sockets were listed
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil, nil
+				}
+				return nil, collectorError("processes", err)
+			}
+			return nil, nil
+		},
+	})
+
+	// Values of the samples leave out the fields reading History or the
+	// collectors rather than the sample, and the history itself
+	sampledCoreType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "SampledCore",
+		Description: coreType.Description(),
+		Fields:      fieldsWithout(coreType, "utilization"),
+	})
+	sampledCPUFields := fieldsWithout(cpuType, "utilization", "history")
+	sampledCPUFields["cores"].Type = graphql.NewNonNull(graphql.NewList(sampledCoreType))
+	sampledCPUType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "SampledCPU",
+		Description: cpuType.Description(),
+		Fields:      sampledCPUFields,
+	})
+	sampledMemoryType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "SampledMemory",
+		Description: memoryType.Description(),
+		Fields:      fieldsWithout(memoryType, "history"),
+	})
+	sampledDiskType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "SampledDisk",
+		Description: diskType.Description(),
+		Fields:      fieldsWithout(diskType, "partitions", "io", "history"),
+	})
+	sampledNetworkType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "SampledNetwork",
+		Description: networkType.Description(),
+		Fields:      fieldsWithout(networkType, "connections", "listeners", "interfaces", "rate", "history"),
+	})
+
+	cpuType.AddFieldConfig("history", historyField("CPU", sampledCPUType,
+		func(sample Sample, source interface{}) interface{} {
+			return sample.CPU
+		}))
+	memoryType.AddFieldConfig("history", historyField("Memory", sampledMemoryType,
+		func(sample Sample, source interface{}) interface{} {
+			return sample.Memory
+		}))
+	diskType.AddFieldConfig("history", historyField("Disk", sampledDiskType,
+		func(sample Sample, source interface{}) interface{} {
+			device := ""
+			if disk, ok := source.(Disk); ok {
+				device = disk.Device
+			}
+			return sample.disk(device)
+		}))
+	networkType.AddFieldConfig("history", historyField("Network", sampledNetworkType,
+		func(sample Sample, source interface{}) interface{} {
+			return sample.Network
+		}))
+}
+
+type historyPoint struct {
+	Timestamp time.Time
+	Value     interface{}
+}
+
+// historyField builds a field listing the samples kept by History, value
+// picks the part of the sample matching valueType.
+func historyField(name string, valueType *graphql.Object, value func(Sample, interface{}) interface{}) *graphql.Field {
+	sampleType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        name + "Sample",
+		Description: "Timestamped " + valueType.Description(),
+		Fields: graphql.Fields{
+			"timestamp": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.DateTime),
+				Description: "When the sample was taken",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if point, ok := p.Source.(historyPoint); ok {
+						return point.Timestamp, nil
+					}
+					return nil, nil
+				},
+			},
+			"value": &graphql.Field{
+				Type:        valueType,
+				Description: "Sampled value",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if point, ok := p.Source.(historyPoint); ok {
+						return point.Value, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(sampleType)),
+		Description: "Samples taken by the background collector",
+		Args: graphql.FieldConfigArgument{
+			"from": &graphql.ArgumentConfig{
+				Type:        graphql.DateTime,
+				Description: "Start of the time range, defaults to the oldest sample",
+			},
+			"to": &graphql.ArgumentConfig{
+				Type:        graphql.DateTime,
+				Description: "End of the time range, defaults to now",
+			},
+			"step": &graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "Minimum time between two samples (e.g. 30s, 5m), widened to return at most 1000 samples",
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			from, _ := p.Args["from"].(time.Time)
+			to, ok := p.Args["to"].(time.Time)
+			if !ok {
+				to = time.Now()
+			}
+
+			var step time.Duration
+			if p.Args["step"] != nil {
+				var err error
+				if step, err = time.ParseDuration(p.Args["step"].(string)); err != nil {
+					return nil, err
+				}
+			}
+
+			points := []historyPoint{}
+			for _, sample := range History.Range(from, to, step, MaxHistoryPoints) {
+				points = append(points, historyPoint{
+					Timestamp: sample.Timestamp,
+					Value:     value(sample, p.Source),
+				})
+			}
+			return points, nil
+		},
+	}
+}
+
+// fieldsWithout copies the fields of object but the ones called omit.
+func fieldsWithout(object *graphql.Object, omit ...string) graphql.Fields {
+	fields := graphql.Fields{}
+	for name, def := range object.Fields() {
+		if slices.Contains(omit, name) {
+			continue
+		}
+
+		args := graphql.FieldConfigArgument{}
+		for _, arg := range def.Args {
+			args[arg.Name()] = &graphql.ArgumentConfig{
+				Type:         arg.Type,
+				DefaultValue: arg.DefaultValue,
+				Description:  arg.Description(),
+			}
+		}
+		fields[name] = &graphql.Field{
+			Type:              def.Type,
+			Args:              args,
+			Resolve:           def.Resolve,
+			Description:       def.Description,
+			DeprecationReason: def.DeprecationReason,
+		}
+	}
+	return fields
+}
+
+// memoryTotal is what the memory amounts are a share of.
+func memoryTotal(source interface{}) uint64 {
+	if mem, ok := source.(Memory); ok {
+		return mem.Total
+	}
+	return 0
+}
+
+// virtualMemoryTotal is what the virtual memory amounts are a share of.
+func virtualMemoryTotal(source interface{}) uint64 {
+	if mem, ok := source.(Memory); ok {
+		return mem.VirtualTotal
+	}
+	return 0
+}
+
+// swapTotal is what the swap amounts are a share of.
+func swapTotal(source interface{}) uint64 {
+	if swap, ok := source.(Swap); ok {
+		return swap.Total
+	}
+	return 0
+}
+
+// cgroupMemoryLimit is what the memory of a cgroup is a share of, its limit
+// or the memory of the host when unlimited.
+func cgroupMemoryLimit(source interface{}) uint64 {
+	if memory, ok := source.(CgroupMemory); ok && memory.Max != nil {
+		return *memory.Max
+	}
+	return hostMemoryTotal(source)
+}
+
+// hostMemoryTotal is the physical memory of the host, which process memory
+// is a share of like in the %MEM column of ps.
+func hostMemoryTotal(source interface{}) uint64 {
+	memory, err := Collectors.Collect(context.Background(), "memory")
+	if err != nil {
+		return 0
+	}
+	return memory.(Memory).Total
+}
+
+// partitionTotal is what the storage amounts of a partition are a share of.
+// Like df, and usedPercent, blocks reserved for root are left out.
+func partitionTotal(source interface{}) uint64 {
+	if partition, ok := asPartition(source); ok {
+		return partition.UsageStat.Used + partition.UsageStat.Free
+	}
+	return 0
+}
+
+// asPartition returns the partition a field is resolved on, either a
+// Partition or a Disk narrowed down to a single device.
+func asPartition(source interface{}) (Partition, bool) {
+	switch source := source.(type) {
+	case Partition:
+		return source, true
+	case Disk:
+		if source.Device != "" && len(source.Partitions) == 1 {
+			return Partition{PartitionStat: source.Partitions[0], UsageStat: source.UsageStat, usageErr: source.usageErr}, true
+		}
+	}
+	return Partition{}, false
+}
+
+// partitionFields builds the fields describing a single partition, shared by
+// the Partition type and the Disk type, where they are only set when a
+// device was given. wrap sets the nullability of the fields but the usage
+// ones, null with an error when the usage can not be read.
+func partitionFields(wrap func(graphql.Output) graphql.Output) graphql.Fields {
+	return graphql.Fields{
+		"device": &graphql.Field{
+			Type:        wrap(graphql.String),
+			Description: "Device name",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if partition, ok := asPartition(p.Source); ok {
+					return partition.Device, nil
+				}
+				return nil, nil
+			},
+		},
+		"fstype": &graphql.Field{
+			Type:        wrap(graphql.String),
+			Description: "Filesystem type",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if partition, ok := asPartition(p.Source); ok {
+					return partition.PartitionStat.Fstype, nil
+				}
+				return nil, nil
+			},
+		},
+		"mountpoint": &graphql.Field{
+			Type:        wrap(graphql.String),
+			Description: "Mount point",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if partition, ok := asPartition(p.Source); ok {
+					return partition.Mountpoint, nil
+				}
+				return nil, nil
+			},
+		},
+		"opts": &graphql.Field{
+			Type:        wrap(graphql.String),
+			Description: "Mount options",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if partition, ok := asPartition(p.Source); ok {
+					return partition.Opts, nil
+				}
+				return nil, nil
+			},
+		},
+		"total": bytesField(&graphql.Field{
+			Type:        BytesScalar,
+			Description: "Total storage space",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if partition, ok := asPartition(p.Source); ok {
+					return partition.UsageStat.Total, partition.usageErr
+				}
+				return nil, nil
+			},
+		}, partitionTotal),
+		"free": bytesField(&graphql.Field{
+			Type:        BytesScalar,
+			Description: "Free storage space",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if partition, ok := asPartition(p.Source); ok {
+					return partition.UsageStat.Free, partition.usageErr
+				}
+				return nil, nil
+			},
+		}, partitionTotal),
+		"used": bytesField(&graphql.Field{
+			Type:        BytesScalar,
+			Description: "Used storage space",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if partition, ok := asPartition(p.Source); ok {
+					return partition.UsageStat.Used, partition.usageErr
+				}
+				return nil, nil
+			},
+		}, partitionTotal),
+		"usedPercent": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "Percentage of the storage space used",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if partition, ok := asPartition(p.Source); ok {
+					return partition.UsageStat.UsedPercent, partition.usageErr
+				}
+				return nil, nil
+			},
+		},
+		"inodestotal": &graphql.Field{
+			Type:        UInt64Scalar,
+			Description: "Total inodes",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if partition, ok := asPartition(p.Source); ok {
+					return partition.UsageStat.InodesTotal, partition.usageErr
+				}
+				return nil, nil
+			},
+		},
+		"inodesused": &graphql.Field{
+			Type:        UInt64Scalar,
+			Description: "Used inodes",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if partition, ok := asPartition(p.Source); ok {
+					return partition.UsageStat.InodesUsed, partition.usageErr
+				}
+				return nil, nil
+			},
+		},
+		"inodesusedPercent": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "Percentage of the inodes used",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if partition, ok := asPartition(p.Source); ok {
+					return partition.UsageStat.InodesUsedPercent, partition.usageErr
+				}
+				return nil, nil
+			},
+		},
+		"inodesfree": &graphql.Field{
+			Type:        UInt64Scalar,
+			Description: "Free inodes",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if partition, ok := asPartition(p.Source); ok {
+					return partition.UsageStat.InodesFree, partition.usageErr
+				}
+				return nil, nil
+			},
+		},
+	}
 }
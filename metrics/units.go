@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+var errPercentUnsupported = errors.New("PERCENT is not supported on this field")
+
+var byteUnitEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "ByteUnit",
+	Description: "Unit of an amount of bytes",
+	Values: graphql.EnumValueConfigMap{
+		"KB": &graphql.EnumValueConfig{
+			Value: float64(1e3),
+		},
+		"MB": &graphql.EnumValueConfig{
+			Value: float64(1e6),
+		},
+		"GB": &graphql.EnumValueConfig{
+			Value: float64(1e9),
+		},
+		"KiB": &graphql.EnumValueConfig{
+			Value: float64(1 << 10),
+		},
+		"MiB": &graphql.EnumValueConfig{
+			Value: float64(1 << 20),
+		},
+		"GiB": &graphql.EnumValueConfig{
+			Value: float64(1 << 30),
+		},
+		"PERCENT": &graphql.EnumValueConfig{
+			Value:       float64(0),
+			Description: "Share of the total the amount is part of",
+		},
+	},
+})
+
+var durationUnitEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "DurationUnit",
+	Description: "Unit of a duration",
+	Values: graphql.EnumValueConfigMap{
+		"SECONDS": &graphql.EnumValueConfig{
+			Value: "SECONDS",
+		},
+		"MILLISECONDS": &graphql.EnumValueConfig{
+			Value: "MILLISECONDS",
+		},
+		"HUMAN": &graphql.EnumValueConfig{
+			Value:       "HUMAN",
+			Description: "Text like 1h2m3s",
+		},
+	},
+})
+
+// bytesField adds the unit argument to a field resolving to an amount of
+// bytes, either a count or a rate. total returns the amount PERCENT is
+// relative to, it is nil on the fields where a share makes no sense.
+func bytesField(field *graphql.Field, total func(source interface{}) uint64) *graphql.Field {
+	resolve := field.Resolve
+
+	if field.Args == nil {
+		field.Args = graphql.FieldConfigArgument{}
+	}
+	field.Args["unit"] = &graphql.ArgumentConfig{
+		Type:        byteUnitEnum,
+		Description: "Unit of the result, defaults to bytes",
+	}
+
+	field.Resolve = func(p graphql.ResolveParams) (interface{}, error) {
+		value, err := resolve(p)
+		if err != nil || value == nil {
+			return value, err
+		}
+
+		unit, ok := p.Args["unit"].(float64)
+		if !ok {
+			return value, nil
+		}
+
+		var amount float64
+		switch value := value.(type) {
+		case uint64:
+			amount = float64(value)
+		case float64:
+			amount = value
+		default:
+			return value, nil
+		}
+
+		if unit == 0 {
+			if total == nil {
+				return nil, errPercentUnsupported
+			}
+			whole := total(p.Source)
+			if whole == 0 {
+				return float64(0), nil
+			}
+			return 100 * amount / float64(whole), nil
+		}
+		return amount / unit, nil
+	}
+
+	return field
+}
+
+// durationField adds the unit argument to a field resolving to a
+// time.Duration.
+func durationField(field *graphql.Field) *graphql.Field {
+	resolve := field.Resolve
+
+	if field.Args == nil {
+		field.Args = graphql.FieldConfigArgument{}
+	}
+	field.Args["unit"] = &graphql.ArgumentConfig{
+		Type:        durationUnitEnum,
+		Description: "Unit of the result, defaults to seconds",
+	}
+
+	field.Resolve = func(p graphql.ResolveParams) (interface{}, error) {
+		value, err := resolve(p)
+		if err != nil {
+			return nil, err
+		}
+
+		d, ok := value.(time.Duration)
+		if !ok {
+			return value, nil
+		}
+
+		switch p.Args["unit"] {
+		case "MILLISECONDS":
+			return float64(d) / float64(time.Millisecond), nil
+		case "HUMAN":
+			if d >= time.Minute {
+				return d.Round(time.Second).String(), nil
+			}
+			return d.Round(time.Millisecond).String(), nil
+		}
+		return d, nil
+	}
+
+	return field
+}
@@ -0,0 +1,397 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// WebSocket sub-protocols, graphql-transport-ws is the current one and
+// graphql-ws the legacy subscriptions-transport-ws protocol.
+const (
+	protocolTransportWS = "graphql-transport-ws"
+	protocolGraphQLWS   = "graphql-ws"
+)
+
+const (
+	wsInitTimeout     = 10 * time.Second
+	wsKeepAlive       = 15 * time.Second
+	wsOutgoingBacklog = 16
+)
+
+// Close codes defined by graphql-transport-ws
+const (
+	wsCloseBadRequest     = 4400
+	wsCloseUnauthorized   = 4401
+	wsCloseForbidden      = 4403
+	wsCloseInitTimeout    = 4408
+	wsCloseSubscriberUsed = 4409
+	wsCloseTooManyInits   = 4429
+)
+
+// WebSocketAuthenticator returns the context the operations of a WebSocket
+// connection are run with, given the context of its upgrade request and the
+// payload of its connection_init message. An error closes the connection.
+type WebSocketAuthenticator func(ctx context.Context, params map[string]interface{}) (context.Context, error)
+
+var wsAuthenticator atomic.Pointer[WebSocketAuthenticator]
+
+// SetWebSocketAuthenticator makes WebSocket clients authenticate with the
+// payload of connection_init, which browsers send in place of the headers
+// they can not set. nil accepts every connection.
+func SetWebSocketAuthenticator(authenticate WebSocketAuthenticator) {
+	if authenticate == nil {
+		wsAuthenticator.Store(nil)
+		return
+	}
+	wsAuthenticator.Store(&authenticate)
+}
+
+var upgrader = websocket.Upgrader{
+	Subprotocols: []string{protocolTransportWS, protocolGraphQLWS},
+}
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type wsRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+type wsConn struct {
+	conn     *websocket.Conn
+	protocol string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	out    chan wsMessage
+
+	mu    sync.Mutex
+	init  bool
+	opCtx context.Context // Of the operations, set by connection_init
+	subs  map[string]context.CancelFunc
+}
+
+// wsConns are the open WebSocket connections, closed by CloseWebSockets.
+var wsConns = struct {
+	sync.Mutex
+	m map[*wsConn]struct{}
+}{m: make(map[*wsConn]struct{})}
+
+// CloseWebSockets tells every WebSocket client that the server is going
+// away and ends their subscriptions. http.Server.Shutdown does not wait for
+// hijacked connections, so it is meant to be registered with
+// RegisterOnShutdown.
+func CloseWebSockets() {
+	wsConns.Lock()
+	defer wsConns.Unlock()
+
+	for c := range wsConns.m {
+		c.close(websocket.CloseGoingAway, "Server shutting down")
+	}
+}
+
+// ServeWebSocket upgrades the request and serves GraphQL operations, most
+// notably subscriptions, over either the graphql-transport-ws or the legacy
+// graphql-ws protocol.
+func ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &wsConn{
+		conn:     conn,
+		protocol: conn.Subprotocol(),
+		out:      make(chan wsMessage, wsOutgoingBacklog),
+		subs:     make(map[string]context.CancelFunc),
+	}
+	if c.protocol == "" {
+		c.protocol = protocolGraphQLWS
+	}
+	c.ctx, c.cancel = context.WithCancel(r.Context())
+
+	wsConns.Lock()
+	wsConns.m[c] = struct{}{}
+	wsConns.Unlock()
+	defer func() {
+		wsConns.Lock()
+		delete(wsConns.m, c)
+		wsConns.Unlock()
+	}()
+
+	go c.writeLoop()
+	c.readLoop()
+}
+
+// send queues a message, blocking while the client is not keeping up so the
+// pressure reaches the subscriptions.
+func (c *wsConn) send(msg wsMessage) {
+	select {
+	case c.out <- msg:
+	case <-c.ctx.Done():
+	}
+}
+
+func (c *wsConn) close(code int, reason string) {
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	c.cancel()
+}
+
+func (c *wsConn) writeLoop() {
+	defer c.conn.Close()
+
+	var keepAlive <-chan time.Time
+	if c.protocol == protocolGraphQLWS {
+		ticker := time.NewTicker(wsKeepAlive)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+
+	for {
+		var msg wsMessage
+		select {
+		case <-c.ctx.Done():
+			return
+		case msg = <-c.out:
+		case <-keepAlive:
+			msg = wsMessage{Type: "ka"}
+		}
+
+		if err := c.conn.WriteJSON(msg); err != nil {
+			c.cancel()
+			return
+		}
+	}
+}
+
+// authenticate sets the context of the operations from the payload of
+// connection_init.
+func (c *wsConn) authenticate(payload json.RawMessage) error {
+	ctx := c.ctx
+	if authenticate := wsAuthenticator.Load(); authenticate != nil {
+		var params map[string]interface{}
+		if len(payload) > 0 {
+			if err := json.Unmarshal(payload, &params); err != nil {
+				return errors.New("Invalid connection parameters")
+			}
+		}
+
+		var err error
+		if ctx, err = (*authenticate)(ctx, params); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	c.opCtx = ctx
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *wsConn) readLoop() {
+	defer c.cancel()
+
+	initTimer := time.AfterFunc(wsInitTimeout, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if !c.init {
+			c.close(wsCloseInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		switch msg.Type {
+		case "connection_init":
+			c.mu.Lock()
+			already := c.init
+			c.init = true
+			c.mu.Unlock()
+
+			if already && c.protocol == protocolTransportWS {
+				c.close(wsCloseTooManyInits, "Too many initialisation requests")
+				return
+			}
+			if err := c.authenticate(msg.Payload); err != nil {
+				c.close(wsCloseForbidden, err.Error())
+				return
+			}
+			c.send(wsMessage{Type: "connection_ack"})
+
+		case "ping":
+			c.send(wsMessage{Type: "pong", Payload: msg.Payload})
+
+		case "pong":
+
+		case "subscribe", "start":
+			c.mu.Lock()
+			initialised := c.init
+			_, exists := c.subs[msg.ID]
+			c.mu.Unlock()
+
+			if !initialised {
+				c.close(wsCloseUnauthorized, "Unauthorized")
+				return
+			}
+			if exists {
+				c.close(wsCloseSubscriberUsed, "Subscriber for "+msg.ID+" already exists")
+				return
+			}
+
+			var req wsRequest
+			if err := json.Unmarshal(msg.Payload, &req); err != nil {
+				c.close(wsCloseBadRequest, "Invalid message payload")
+				return
+			}
+			cost, err := limitQuery(req.Query, req.OperationName)
+			if err != nil {
+				c.sendError(msg.ID, []gqlerrors.FormattedError{formatError(err)})
+				continue
+			}
+			c.start(msg.ID, req, cost)
+
+		case "complete", "stop":
+			c.stop(msg.ID)
+
+		case "connection_terminate":
+			return
+
+		default:
+			c.close(wsCloseBadRequest, "Invalid message received")
+			return
+		}
+	}
+}
+
+// start runs an operation in the background. Subscriptions stream a result
+// per event, queries and mutations a single one, each with the cost of the
+// operation.
+func (c *wsConn) start(id string, req wsRequest, cost int) {
+	c.mu.Lock()
+	ctx, cancel := context.WithCancel(c.opCtx)
+	c.subs[id] = cancel
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.subs, id)
+			c.mu.Unlock()
+			cancel()
+		}()
+
+		params := graphql.Params{
+			Schema:         MetricsSchema,
+			RequestString:  req.Query,
+			VariableValues: req.Variables,
+			OperationName:  req.OperationName,
+			Context:        ctx,
+		}
+
+		var results chan *graphql.Result
+		if operationType(req.Query, req.OperationName) == ast.OperationTypeSubscription {
+			params.Context = withMaxAge(ctx)
+			results = graphql.Subscribe(params)
+		} else {
+			params.Context = WithRequestCache(ctx)
+			results = make(chan *graphql.Result, 1)
+			results <- graphql.Do(params)
+			close(results)
+		}
+
+		failed := false
+		// Drain the channel even after being stopped, so the executor does
+		// not block forever trying to deliver a result
+		for result := range results {
+			if ctx.Err() != nil || failed {
+				continue
+			}
+			if result.Data == nil && result.HasErrors() {
+				c.sendError(id, result.Errors)
+				failed = true
+				continue
+			}
+			if cost > 0 {
+				result.Extensions = map[string]interface{}{"cost": cost}
+			}
+			c.sendResult(id, result)
+		}
+
+		if ctx.Err() == nil && !failed {
+			c.send(wsMessage{ID: id, Type: "complete"})
+		}
+	}()
+}
+
+func (c *wsConn) stop(id string) {
+	c.mu.Lock()
+	cancel, ok := c.subs[id]
+	c.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
+
+func (c *wsConn) sendResult(id string, result *graphql.Result) {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+
+	msgType := "next"
+	if c.protocol == protocolGraphQLWS {
+		msgType = "data"
+	}
+	c.send(wsMessage{ID: id, Type: msgType, Payload: payload})
+}
+
+func (c *wsConn) sendError(id string, errs []gqlerrors.FormattedError) {
+	var payload []byte
+	if c.protocol == protocolGraphQLWS {
+		// The legacy protocol carries a single error object
+		payload, _ = json.Marshal(errs[0])
+	} else {
+		payload, _ = json.Marshal(errs)
+	}
+	c.send(wsMessage{ID: id, Type: "error", Payload: payload})
+}
+
+// operationType returns the type of the operation that will be executed,
+// or an empty string when the document cannot be parsed.
+func operationType(query, operationName string) string {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return ""
+	}
+
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			if operationName == "" || (op.Name != nil && op.Name.Value == operationName) {
+				return op.Operation
+			}
+		}
+	}
+	return ""
+}
@@ -24,9 +24,10 @@ resource "docker_image" "gometric" {
 resource "docker_container" "test1" {
   image = docker_image.gometric.image_id
   name  = "gometric1"
+  env   = ["GOMETRIC_LISTEN=:7001"]
 
   ports {
-    internal = 7000
+    internal = 7001
     external = 7001
   }
 }
@@ -34,9 +35,10 @@ resource "docker_container" "test1" {
 resource "docker_container" "test2" {
   image = docker_image.gometric.image_id
   name  = "gometric2"
+  env   = ["GOMETRIC_LISTEN=:7002"]
 
   ports {
-    internal = 7000
+    internal = 7002
     external = 7002
   }
 }
@@ -44,9 +46,10 @@ resource "docker_container" "test2" {
 resource "docker_container" "test3" {
   image = docker_image.gometric.image_id
   name  = "gometric3"
+  env   = ["GOMETRIC_LISTEN=:7003"]
 
   ports {
-    internal = 7000
+    internal = 7003
     external = 7003
   }
 }
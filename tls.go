@@ -0,0 +1,211 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/davidjosearaujo/gometric/metrics"
+)
+
+// TLSConfig serves the endpoints over TLS when Cert and Key are set, and
+// verifies client certificates against ClientCA when set.
+type TLSConfig struct {
+	Cert              string `yaml:"cert"`
+	Key               string `yaml:"key"`
+	ClientCA          string `yaml:"clientCA"`
+	RequireClientCert bool   `yaml:"requireClientCert"` // Otherwise only verified when given
+
+	// Role of the clients, by the subject (e.g. CN=grafana,O=Example) or the
+	// common name of their certificate
+	Roles map[string]string `yaml:"roles"`
+}
+
+func (c TLSConfig) enabled() bool {
+	return c.Cert != "" || c.Key != ""
+}
+
+func (c TLSConfig) validate() []error {
+	var errs []error
+
+	if c.enabled() {
+		if c.Cert == "" || c.Key == "" {
+			errs = append(errs, errors.New("tls: cert and key must be set together"))
+		} else if _, err := tls.LoadX509KeyPair(c.Cert, c.Key); err != nil {
+			errs = append(errs, fmt.Errorf("tls.cert: %w", err))
+		}
+	}
+
+	if c.ClientCA != "" {
+		if !c.enabled() {
+			errs = append(errs, errors.New("tls.clientCA: client certificates need cert and key"))
+		} else if _, err := loadCertPool(c.ClientCA); err != nil {
+			errs = append(errs, fmt.Errorf("tls.clientCA: %w", err))
+		}
+	} else {
+		if c.RequireClientCert {
+			errs = append(errs, errors.New("tls.requireClientCert: clientCA is not set"))
+		}
+		if len(c.Roles) > 0 {
+			errs = append(errs, errors.New("tls.roles: clientCA is not set"))
+		}
+	}
+
+	return errs
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", file)
+	}
+	return pool, nil
+}
+
+// tlsFiles hands out the certificate and the client CAs of a TLSConfig,
+// loading them again when their files change on disk.
+type tlsFiles struct {
+	mu       sync.Mutex
+	config   TLSConfig
+	checked  time.Time
+	modTimes [3]time.Time // Of Cert, Key and ClientCA
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+}
+
+// tlsCheckInterval is how often the files are looked at, at most.
+const tlsCheckInterval = 5 * time.Second
+
+func newTLSFiles(config TLSConfig) (*tlsFiles, error) {
+	f := &tlsFiles{}
+	return f, f.update(config)
+}
+
+// update switches to the files of config, and keeps the previous ones when
+// they can not be loaded.
+func (f *tlsFiles) update(config TLSConfig) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	previous, modTimes := f.config, f.modTimes
+	f.config, f.modTimes = config, [3]time.Time{}
+	if err := f.load(); err != nil {
+		f.config, f.modTimes = previous, modTimes
+		return err
+	}
+	return nil
+}
+
+// load reads the files when their modification time changed. The caller
+// must hold the lock.
+func (f *tlsFiles) load() error {
+	var modTimes [3]time.Time
+	for i, file := range []string{f.config.Cert, f.config.Key, f.config.ClientCA} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	f.checked = time.Now()
+	if f.cert != nil && modTimes == f.modTimes {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(f.config.Cert, f.config.Key)
+	if err != nil {
+		return err
+	}
+	var clientCA *x509.CertPool
+	if f.config.ClientCA != "" {
+		if clientCA, err = loadCertPool(f.config.ClientCA); err != nil {
+			return err
+		}
+	}
+
+	if f.cert != nil {
+		log.Printf("TLS certificates reloaded")
+	}
+	f.cert, f.clientCA, f.modTimes = &cert, clientCA, modTimes
+	return nil
+}
+
+// current returns the certificate and the client CAs, checking the files
+// first when they were not looked at for a while. Files that fail to load,
+// like a certificate written before its key, are tried again later.
+func (f *tlsFiles) current() (*tls.Certificate, *x509.CertPool, TLSConfig) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if time.Since(f.checked) >= tlsCheckInterval {
+		if err := f.load(); err != nil {
+			log.Printf("TLS certificates not reloaded: %v", err)
+		}
+	}
+	return f.cert, f.clientCA, f.config
+}
+
+// serverConfig returns the TLS configuration of the server, which picks up
+// the current files on every handshake.
+func (f *tlsFiles) serverConfig() *tls.Config {
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// http.Server only adds them to its own copy of the configuration,
+		// which the one of each client would not be based on
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+
+	config := base.Clone()
+	config.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert, _, _ := f.current()
+		return cert, nil
+	}
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		cert, clientCA, settings := f.current()
+
+		c := base.Clone()
+		c.Certificates = []tls.Certificate{*cert}
+		if clientCA != nil {
+			c.ClientCAs = clientCA
+			c.ClientAuth = tls.VerifyClientCertIfGiven
+			if settings.RequireClientCert {
+				c.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+		}
+		return c, nil
+	}
+	return config
+}
+
+// withClientRole gives requests made with a verified client certificate the
+// role its subject is mapped to.
+func (f *tlsFiles) withClientRole(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			_, _, config := f.current()
+			subject := r.TLS.VerifiedChains[0][0].Subject
+
+			role, ok := config.Roles[subject.String()]
+			if !ok {
+				role, ok = config.Roles[subject.CommonName]
+			}
+			if ok {
+				r = r.WithContext(metrics.WithRole(r.Context(), role))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}